@@ -0,0 +1,401 @@
+package main
+
+/*
+##############################################################
+# Section: Imports
+##############################################################
+*/
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+/*
+######################################################################################################
+######################################################################################################
+## Chapter: Canvas																					##
+######################################################################################################
+######################################################################################################
+*/
+
+/*
+##############################################################
+# Section: Constants & Fields
+##############################################################
+*/
+
+// What items draw onto. There is one for sdl surfaces (the windows) and one for
+// images (see ImageCanvas), so items do not depend on how the result is shown.
+//
+// Items draw from (0, 0) to their size: containers move the canvas to each item (Translate)
+// and cut off everything outside of it (Clip). Colors are 0xrrggbb, like ColorRole.RGB().
+type Canvas interface {
+	// Fills a rectangle with a color
+	FillRect(position Vector, size Vector, color uint32) error
+
+	// Draws a picture at its size, blending it onto what is below
+	DrawPicture(pic *Picture, position Vector) error
+
+	// Draws a line of text, position is the top left corner of its line
+	DrawText(text string, font *LoadedFont, color uint32, position Vector) error
+
+	// Moves (0, 0) by offset
+	Translate(offset Vector)
+
+	// Cuts off everything drawn outside of the rectangle (and outside of the previous clip)
+	Clip(position Vector, size Vector)
+
+	// Remembers the translation and clip, until Restore brings them back
+	Save()
+	Restore()
+}
+
+// An image the items can draw (icons, desktop images).
+// The sdl canvas makes a surface out of it the first time it is drawn, Free frees that.
+type Picture struct {
+	img     *image.RGBA
+	surface *sdl.Surface
+}
+
+// The translation and clip of a canvas, in the coordinates of what is drawn onto
+type canvasState struct {
+	offset Vector
+
+	clip_position Vector
+	clip_size     Vector
+}
+
+// Implements Translate, Clip, Save and Restore for both canvases
+type canvasStates struct {
+	state canvasState
+	saved []canvasState
+}
+
+// The font files opened by the image canvas
+var image_fonts = make(map[string]*opentype.Font)
+
+// A font opened with opentype, for the image canvas (see useImageFonts)
+type imageFace struct {
+	face font.Face
+}
+
+/*
+##############################################################
+# Section: Pictures
+##############################################################
+*/
+
+// Makes a picture out of an image
+func NewPicture(img image.Image) (pic *Picture) {
+	rgba, ok := img.(*image.RGBA)
+	if !ok || rgba.Rect.Min != (image.Point{}) {
+		bounds := img.Bounds()
+		rgba = image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(rgba, rgba.Rect, img, bounds.Min, draw.Src)
+	}
+
+	return &Picture{img: rgba}
+}
+
+// Gets a picture of the given size, filled with a color (0 is transparent)
+func NewFilledPicture(size Vector, rgb uint32) (pic *Picture) {
+	img := image.NewRGBA(image.Rect(0, 0, int(size.x), int(size.y)))
+	if rgb != 0 {
+		draw.Draw(img, img.Rect, image.NewUniform(rgbToColor(rgb)), image.Point{}, draw.Src)
+	}
+	return &Picture{img: img}
+}
+
+// Gets the size of the picture
+func (pic *Picture) Size() (size Vector) {
+	return Vector{int32(pic.img.Rect.Dx()), int32(pic.img.Rect.Dy())}
+}
+
+// Frees the surface of the picture (it is created again when the picture is drawn again)
+func (pic *Picture) Free() {
+	if pic.surface != nil {
+		pic.surface.Free()
+		pic.surface = nil
+	}
+}
+
+// Gets the picture as a surface, creating it the first time
+func (pic *Picture) getSurface() (surface *sdl.Surface, err error) {
+	if pic.surface == nil {
+		pic.surface, err = ImgTosurface(pic.img)
+	}
+	return pic.surface, err
+}
+
+// Converts a color of the theme into an opaque color
+func rgbToColor(rgb uint32) color.RGBA {
+	return color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 0xff}
+}
+
+/*
+##############################################################
+# Section: Transformation
+##############################################################
+*/
+
+// Starts out with the whole area of size visible
+func newCanvasStates(size Vector) canvasStates {
+	return canvasStates{state: canvasState{clip_size: size}}
+}
+
+func (states *canvasStates) Translate(offset Vector) {
+	states.state.offset = states.state.offset.add(offset)
+}
+
+func (states *canvasStates) Clip(position Vector, size Vector) {
+	position, size, _ = states.visible(position, size)
+	states.state.clip_position = position
+	states.state.clip_size = size
+}
+
+func (states *canvasStates) Save() {
+	states.saved = append(states.saved, states.state)
+}
+
+func (states *canvasStates) Restore() {
+	if len(states.saved) == 0 {
+		return
+	}
+	states.state = states.saved[len(states.saved)-1]
+	states.saved = states.saved[:len(states.saved)-1]
+}
+
+// Gets the part of a rectangle that is inside of the clip, in the coordinates of what is drawn onto.
+// visible is false if nothing of it is.
+func (states *canvasStates) visible(position Vector, size Vector) (visible_position Vector, visible_size Vector, visible bool) {
+	start := position.add(states.state.offset)
+	end := start.add(size)
+
+	clip_end := states.state.clip_position.add(states.state.clip_size)
+
+	if start.x < states.state.clip_position.x {
+		start.x = states.state.clip_position.x
+	}
+	if start.y < states.state.clip_position.y {
+		start.y = states.state.clip_position.y
+	}
+	if end.x > clip_end.x {
+		end.x = clip_end.x
+	}
+	if end.y > clip_end.y {
+		end.y = clip_end.y
+	}
+
+	if end.x <= start.x || end.y <= start.y {
+		return start, Vector{0, 0}, false
+	}
+	return start, end.sub(start), true
+}
+
+/*
+##############################################################
+# Section: SDL
+##############################################################
+*/
+
+// Draws onto an sdl surface (like the one of a window)
+type SurfaceCanvas struct {
+	canvasStates
+	surface *sdl.Surface
+}
+
+// Gets a canvas drawing onto the whole surface
+func NewSurfaceCanvas(surface *sdl.Surface) (canvas *SurfaceCanvas) {
+	return &SurfaceCanvas{canvasStates: newCanvasStates(Vector{surface.W, surface.H}), surface: surface}
+}
+
+func (canvas *SurfaceCanvas) FillRect(position Vector, size Vector, color uint32) (err error) {
+	position, size, ok := canvas.visible(position, size)
+	if !ok {
+		return nil
+	}
+	return canvas.surface.FillRect(&sdl.Rect{X: position.x, Y: position.y, W: size.x, H: size.y}, color)
+}
+
+func (canvas *SurfaceCanvas) DrawPicture(pic *Picture, position Vector) (err error) {
+	surface, err := pic.getSurface()
+	if err != nil {
+		return err
+	}
+	return canvas.blit(surface, position)
+}
+
+func (canvas *SurfaceCanvas) DrawText(text string, font *LoadedFont, color uint32, position Vector) (err error) {
+	// ttf can not render nothing
+	if text == "" {
+		return nil
+	}
+
+	text_surface, err := font.RenderUTF8Blended(text, UInt32ToColor(color|0xff000000))
+	if err != nil {
+		return err
+	}
+	defer text_surface.Free()
+
+	return canvas.blit(text_surface, position)
+}
+
+// Draws the visible part of a surface
+func (canvas *SurfaceCanvas) blit(surface *sdl.Surface, position Vector) (err error) {
+	visible_position, visible_size, ok := canvas.visible(position, Vector{surface.W, surface.H})
+	if !ok {
+		return nil
+	}
+
+	// where the visible part starts in the surface
+	start := visible_position.sub(position.add(canvas.state.offset))
+
+	src_rect := sdl.Rect{X: start.x, Y: start.y, W: visible_size.x, H: visible_size.y}
+	dst_rect := sdl.Rect{X: visible_position.x, Y: visible_position.y, W: visible_size.x, H: visible_size.y}
+	return surface.Blit(&src_rect, canvas.surface, &dst_rect)
+}
+
+/*
+##############################################################
+# Section: Image
+##############################################################
+*/
+
+// Draws onto an image, without sdl. Text is drawn with opentype, from the same font files
+// as ttf, so the fonts have to be opened for it (see useImageFonts).
+// Used to render windows without a display, and by the tests.
+type ImageCanvas struct {
+	canvasStates
+	img *image.RGBA
+}
+
+// Gets a canvas drawing onto the whole image
+func NewImageCanvas(img *image.RGBA) (canvas *ImageCanvas) {
+	return &ImageCanvas{canvasStates: newCanvasStates(Vector{int32(img.Rect.Dx()), int32(img.Rect.Dy())}), img: img}
+}
+
+// Gets the part of the image that can be drawn onto (drawing outside of sub images does nothing)
+func (canvas *ImageCanvas) clipped() (img *image.RGBA) {
+	position := canvas.state.clip_position
+	end := position.add(canvas.state.clip_size)
+	return canvas.img.SubImage(image.Rect(int(position.x), int(position.y), int(end.x), int(end.y))).(*image.RGBA)
+}
+
+func (canvas *ImageCanvas) FillRect(position Vector, size Vector, color uint32) (err error) {
+	position, size, ok := canvas.visible(position, size)
+	if !ok {
+		return nil
+	}
+
+	rect := image.Rect(int(position.x), int(position.y), int(position.x+size.x), int(position.y+size.y))
+	draw.Draw(canvas.img, rect, image.NewUniform(rgbToColor(color)), image.Point{}, draw.Src)
+	return nil
+}
+
+func (canvas *ImageCanvas) DrawPicture(pic *Picture, position Vector) (err error) {
+	position = position.add(canvas.state.offset)
+	rect := pic.img.Rect.Add(image.Pt(int(position.x), int(position.y)))
+
+	draw.Draw(canvas.clipped(), rect, pic.img, image.Point{}, draw.Over)
+	return nil
+}
+
+func (canvas *ImageCanvas) DrawText(text string, loaded_font *LoadedFont, color uint32, position Vector) (err error) {
+	runs := loaded_font.runs(text)
+
+	// like ttf, the runs go onto the highest baseline of their fonts
+	faces := make([]*imageFace, len(runs))
+	ascent := 0
+	for i, run := range runs {
+		face, ok := run.font.face.(*imageFace)
+		if !ok {
+			return fmt.Errorf("%s was not opened for images (see useImageFonts)", run.font.path)
+		}
+		faces[i] = face
+
+		if face.Ascent() > ascent {
+			ascent = face.Ascent()
+		}
+	}
+
+	position = position.add(canvas.state.offset)
+
+	drawer := font.Drawer{
+		Dst: canvas.clipped(),
+		Src: image.NewUniform(rgbToColor(color)),
+		Dot: fixed.P(int(position.x), int(position.y)+ascent),
+	}
+
+	for i, run := range runs {
+		drawer.Face = faces[i].face
+		drawer.DrawString(run.text)
+	}
+
+	return nil
+}
+
+/*
+##############################################################
+# Section: Image fonts
+##############################################################
+*/
+
+// Makes the fonts opened from now on draw with the image canvas instead of sdl_ttf.
+// The fonts that are open already are closed, so they are opened again.
+func useImageFonts() {
+	closeFonts()
+	openFontFace = openImageFace
+}
+
+// Opens a font file with opentype, at the same size as ttf would
+func openImageFace(path string, size int) (face FontFace, err error) {
+	parsed, ok := image_fonts[path]
+	if !ok {
+		parsed, err = parseFontFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		image_fonts[path] = parsed
+	}
+
+	// ttf sizes are points at 72 dpi, so pixels
+	opened, err := opentype.NewFace(parsed, &opentype.FaceOptions{Size: float64(size), DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, err
+	}
+
+	return &imageFace{face: opened}, nil
+}
+
+// Like ttf, the text is as wide as its glyphs if they reach out further than their advance
+func (face *imageFace) SizeUTF8(text string) (w int, h int, err error) {
+	bounds, advance := font.BoundString(face.face, text)
+	if bounds.Max.X > advance {
+		advance = bounds.Max.X
+	}
+	return advance.Ceil(), face.Height(), nil
+}
+
+// Like ttf, a line is high enough for the ascent and descent of the font
+// (the line spacing of opentype can be less than that)
+func (face *imageFace) Height() int {
+	metrics := face.face.Metrics()
+	return metrics.Ascent.Ceil() + metrics.Descent.Ceil()
+}
+
+func (face *imageFace) Ascent() int {
+	return face.face.Metrics().Ascent.Ceil()
+}
+
+func (face *imageFace) Close() {
+	face.face.Close()
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// Makes the items draw with the image canvas, in the dark theme with the Go fonts,
+// so the tests depend neither on sdl nor on the fonts that are installed
+func useTestTheme(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+	regular := filepath.Join(dir, "Go-Regular.ttf")
+	bold := filepath.Join(dir, "Go-Bold.ttf")
+	for path, data := range map[string][]byte{regular: goregular.TTF, bold: gobold.TTF} {
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	old_theme := current_theme
+	current_theme = builtin_themes[DEFAULT_THEME]
+	current_theme.Fonts = [FONT_ROLE_COUNT]Font{
+		{regular, "", 32},
+		{bold, "", 20},
+		{regular, "", 16},
+		{regular, "", 12},
+	}
+	background_color = BACKGROUND.RGB()
+
+	useImageFonts()
+
+	t.Cleanup(func() {
+		closeFonts()
+		openFontFace = openTTFFace
+		current_theme = old_theme
+	})
+}
+
+// Gets the smallest rectangle with all pixels that are not the background
+func drawnBounds(img *image.RGBA, background uint32) (bounds image.Rectangle) {
+	bg := rgbToColor(background)
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			if img.RGBAAt(x, y) != bg {
+				bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return bounds
+}
+
+// Checks if a pixel has exactly a color of the theme
+func hasColor(img *image.RGBA, x int, y int, rgb uint32) bool {
+	return img.RGBAAt(x, y) == rgbToColor(rgb)
+}
+
+func TestImageCanvasClip(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	canvas := NewImageCanvas(img)
+
+	canvas.Save()
+	canvas.Translate(Vector{2, 2})
+	canvas.Clip(Vector{0, 0}, Vector{4, 4})
+	canvas.FillRect(Vector{-5, -5}, Vector{100, 100}, 0xff0000)
+
+	// a nested clip is cut off by the one around it
+	canvas.Save()
+	canvas.Translate(Vector{3, 3})
+	canvas.Clip(Vector{0, 0}, Vector{10, 10})
+	canvas.DrawPicture(NewFilledPicture(Vector{5, 5}, 0x00ff00), Vector{0, 0})
+	canvas.Restore()
+	canvas.Restore()
+
+	// back to the whole image
+	canvas.FillRect(Vector{9, 9}, Vector{5, 5}, 0x0000ff)
+
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			want := color.RGBA{}
+			switch {
+			case x == 9 && y == 9:
+				want = rgbToColor(0x0000ff)
+			case x == 5 && y == 5:
+				want = rgbToColor(0x00ff00)
+			case x >= 2 && x < 6 && y >= 2 && y < 6:
+				want = rgbToColor(0xff0000)
+			}
+
+			if got := img.RGBAAt(x, y); got != want {
+				t.Errorf("pixel %d,%d is %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestImageCanvasTransparentPicture(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	canvas := NewImageCanvas(img)
+	canvas.FillRect(Vector{0, 0}, Vector{4, 4}, 0x123456)
+
+	// transparent pictures leave what is below
+	canvas.DrawPicture(NewFilledPicture(Vector{4, 4}, 0), Vector{0, 0})
+
+	if !hasColor(img, 2, 2, 0x123456) {
+		t.Errorf("a transparent picture changed the pixel to %v", img.RGBAAt(2, 2))
+	}
+}
+
+// The text has to be drawn where it was measured, or the items cut it off
+func TestImageCanvasTextSize(t *testing.T) {
+	useTestTheme(t)
+
+	font, err := getFont(HEADER_FONT)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, h, err := font.SizeUTF8("Sidebag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w == 0 || h != font.Height() {
+		t.Fatalf("measured %dx%d for a font %d pixels high", w, h, font.Height())
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, w+20, h+20))
+	canvas := NewImageCanvas(img)
+	canvas.FillRect(Vector{0, 0}, Vector{int32(w + 20), int32(h + 20)}, BACKGROUND.RGB())
+	err = canvas.DrawText("Sidebag", font, FOREGROUND.RGB(), Vector{10, 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	drawn := drawnBounds(img, BACKGROUND.RGB())
+	if drawn.Empty() {
+		t.Fatal("nothing was drawn")
+	}
+	if measured := image.Rect(10, 10, 10+w, 10+h); !drawn.In(measured) {
+		t.Errorf("the text was drawn at %v, outside of where it was measured (%v)", drawn, measured)
+	}
+}
+
+func TestImageCanvasNeedsImageFonts(t *testing.T) {
+	font := &LoadedFont{path: "test.ttf", face: nil, fallbacks: make(map[rune]*LoadedFont)}
+	canvas := NewImageCanvas(image.NewRGBA(image.Rect(0, 0, 10, 10)))
+
+	if err := canvas.DrawText("a", font, 0xffffff, Vector{0, 0}); err == nil {
+		t.Error("drew with a font that was not opened for images")
+	}
+}
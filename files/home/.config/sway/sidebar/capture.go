@@ -0,0 +1,379 @@
+package main
+
+/*
+##############################################################
+# Section: Imports
+##############################################################
+*/
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+######################################################################################################
+######################################################################################################
+## Chapter: Capture Daemon																			##
+######################################################################################################
+######################################################################################################
+*/
+
+/*
+##############################################################
+# Section: Constants & Fields
+##############################################################
+*/
+
+// The command used to take a screenshot. It has to write a png to stdout.
+// %g is replaced with the geometry ("x,y wxh") and %o with the output name.
+var DEFAULT_CAPTURE_COMMAND = []string{"grim", "-g", "%g", "-"}
+
+// Screenshots are scaled down by this factor before saving
+const CAPTURE_DOWNSCALE = 8
+
+// Time to wait after the last change before taking a screenshot, so sway can render first
+const CAPTURE_DELAY = 150 * time.Millisecond
+
+/*
+##############################################################
+# Section: Daemon
+##############################################################
+*/
+
+// Keeps the desktop images (see getDesktopImagesPath) up to date with an image of every workspace.
+//
+// Sway only reports a focus change after the old workspace is gone from the screen,
+// so the daemon keeps a screenshot of the focused workspace in memory (retaken whenever
+// its windows change) and saves it as the image of that workspace once it is left.
+func runCaptureDaemon(command []string) (err error) {
+	if len(command) == 0 {
+		command = DEFAULT_CAPTURE_COMMAND
+	}
+
+	queries, err := ConnectIPC()
+	if err != nil {
+		return err
+	}
+	defer queries.Close()
+
+	events, err := ConnectIPC()
+	if err != nil {
+		return err
+	}
+	defer events.Close()
+
+	err = events.Subscribe("workspace", "window")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(getDesktopImagesPath(), 0755)
+	if err != nil {
+		return err
+	}
+
+	// the last screenshot of the focused workspace. The screenshots are taken in the background.
+	var mutex sync.Mutex
+	var current_name string
+	var current_img image.Image
+
+	// while a sidebar window is focused, the screenshots would show it
+	var sidebar_focused bool
+
+	// both the screenshots and the event loop ask for the workspaces
+	var queries_mutex sync.Mutex
+	getWorkspaces := func() (workspaces []Workspace, err error) {
+		queries_mutex.Lock()
+		defer queries_mutex.Unlock()
+		return queries.GetWorkspaces()
+	}
+
+	// only one screenshot is taken at a time, so an older one never replaces a newer one
+	var snapshot_mutex sync.Mutex
+
+	snapshot := func() {
+		snapshot_mutex.Lock()
+		defer snapshot_mutex.Unlock()
+
+		mutex.Lock()
+		skip := sidebar_focused
+		mutex.Unlock()
+		if skip {
+			return
+		}
+
+		workspaces, err := getWorkspaces()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		for _, ws := range workspaces {
+			if !ws.Focused {
+				continue
+			}
+
+			img, err := captureWorkspace(command, ws)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+
+			mutex.Lock()
+			// the sidebar may have been opened while the screenshot was taken
+			if !sidebar_focused {
+				current_name, current_img = ws.Name, img
+			}
+			mutex.Unlock()
+		}
+	}
+
+	// Changes come in bursts (e.g. a new window also resizes the others), so the screenshot is taken
+	// once no change came for CAPTURE_DELAY. The timer runs it in its own goroutine,
+	// so the events are still handled meanwhile.
+	timer := time.AfterFunc(CAPTURE_DELAY, snapshot)
+	defer timer.Stop()
+
+	for {
+		event, err := events.NextEvent()
+		if err != nil {
+			return err
+		}
+
+		switch event.Type {
+		case IPC_EVENT_WORKSPACE:
+			wevent, err := event.Workspace()
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+
+			if wevent.Change == "focus" {
+				// save the workspace that was left
+				mutex.Lock()
+				name, img := current_name, current_img
+				mutex.Unlock()
+
+				if wevent.Old != nil && wevent.Old.Name == name && img != nil {
+					err = saveDesktopImage(name, img)
+					if err != nil {
+						fmt.Println(err)
+					}
+				}
+
+				timer.Reset(CAPTURE_DELAY)
+			}
+
+			// workspaces may have been created, renamed or destroyed
+			workspaces, err := getWorkspaces()
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+
+			err = pruneDesktopImages(workspaces)
+			if err != nil {
+				fmt.Println(err)
+			}
+
+		case IPC_EVENT_WINDOW:
+			wevent, err := event.Window()
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+
+			is_sidebar := wevent.Container != nil && wevent.Container.Name == WINDOW_TITLE
+
+			switch wevent.Change {
+			// focus only changes the border colors, but tells whether a sidebar window is in front
+			case "focus":
+				mutex.Lock()
+				sidebar_focused = is_sidebar
+				mutex.Unlock()
+				continue
+
+			// without windows left, the workspace is focused instead of another window
+			case "close":
+				if is_sidebar {
+					mutex.Lock()
+					sidebar_focused = false
+					mutex.Unlock()
+				}
+
+			// these do not change what the workspace looks like (much)
+			case "title", "mark", "urgent":
+				continue
+			}
+
+			timer.Reset(CAPTURE_DELAY)
+		}
+	}
+}
+
+// Takes a downscaled screenshot of a workspace using the capture command
+func captureWorkspace(command []string, ws Workspace) (img image.Image, err error) {
+	geometry := fmt.Sprintf("%d,%d %dx%d", ws.Rect.X, ws.Rect.Y, ws.Rect.Width, ws.Rect.Height)
+
+	args := make([]string, len(command))
+	for i, arg := range command {
+		arg = strings.Replace(arg, "%g", geometry, -1)
+		arg = strings.Replace(arg, "%o", ws.Output, -1)
+		args[i] = arg
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	img, err = png.Decode(&stdout)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	return scaleImage(img, Vector{int32(bounds.Dx() / CAPTURE_DOWNSCALE), int32(bounds.Dy() / CAPTURE_DOWNSCALE)}), nil
+}
+
+// Scales an image down by averaging the pixels covered by each target pixel
+func scaleImage(img image.Image, newsize Vector) (scaled *image.RGBA) {
+	if newsize.x < 1 {
+		newsize.x = 1
+	}
+	if newsize.y < 1 {
+		newsize.y = 1
+	}
+
+	scaled = image.NewRGBA(image.Rect(0, 0, int(newsize.x), int(newsize.y)))
+	bounds := img.Bounds()
+
+	for y := 0; y < int(newsize.y); y++ {
+		// the source rows covered by this pixel
+		y0 := bounds.Min.Y + y*bounds.Dy()/int(newsize.y)
+		y1 := bounds.Min.Y + (y+1)*bounds.Dy()/int(newsize.y)
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+
+		for x := 0; x < int(newsize.x); x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/int(newsize.x)
+			x1 := bounds.Min.X + (x+1)*bounds.Dx()/int(newsize.x)
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, b, a = r+pr, g+pg, b+pb, a+pa
+					n++
+				}
+			}
+
+			i := scaled.PixOffset(x, y)
+			scaled.Pix[i+0] = uint8(r / n >> 8)
+			scaled.Pix[i+1] = uint8(g / n >> 8)
+			scaled.Pix[i+2] = uint8(b / n >> 8)
+			scaled.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+
+	return scaled
+}
+
+/*
+##############################################################
+# Section: Image files
+##############################################################
+*/
+
+// Gets the directory the desktop images are stored in.
+// They are recreated all the time, so they are cache.
+func getDesktopImagesPath() (path string) {
+	cache_dir, err := getCacheDir()
+	if err != nil {
+		// without a home directory, they are still shared between the sidebar windows
+		cache_dir = filepath.Join(os.TempDir(), SIDEBAR_SUBDIR)
+	}
+
+	return filepath.Join(cache_dir, "desktops")
+}
+
+// Gets the path of the image of a workspace.
+// Workspace names may contain slashes, so they are escaped.
+func getDesktopImagePath(name string) (path string) {
+	return filepath.Join(getDesktopImagesPath(), url.PathEscape(name)+".png")
+}
+
+// Writes the image of a workspace, replacing the old one atomically
+func saveDesktopImage(name string, img image.Image) (err error) {
+	path := getDesktopImagePath(name)
+
+	// write into a temporary file in the same directory first,
+	// so the desktop window never sees a half written image
+	file, err := os.CreateTemp(filepath.Dir(path), ".capture-*.png")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	err = png.Encode(file, img)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	err = file.Sync()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+// Deletes the images of workspaces that no longer exist
+func pruneDesktopImages(workspaces []Workspace) (err error) {
+	keep := make(map[string]struct{})
+	for _, ws := range workspaces {
+		keep[getDesktopImagePath(ws.Name)] = struct{}{}
+	}
+
+	paths, err := filepath.Glob(filepath.Join(getDesktopImagesPath(), "*.png"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		if _, ok := keep[path]; !ok {
+			err = os.Remove(path)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestScaleImage(t *testing.T) {
+	// 4x2 pixels: black and white columns on the left, red on the right
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		img.SetRGBA(0, y, color.RGBA{0, 0, 0, 255})
+		img.SetRGBA(1, y, color.RGBA{255, 255, 255, 255})
+		img.SetRGBA(2, y, color.RGBA{255, 0, 0, 255})
+		img.SetRGBA(3, y, color.RGBA{255, 0, 0, 255})
+	}
+
+	scaled := scaleImage(img, Vector{2, 1})
+	if scaled.Rect != image.Rect(0, 0, 2, 1) {
+		t.Fatalf("scaled to %v, want 2x1", scaled.Rect)
+	}
+
+	// each pixel is the average of what it covers
+	if got, want := scaled.RGBAAt(0, 0), (color.RGBA{127, 127, 127, 255}); got != want {
+		t.Errorf("left pixel is %v, want %v", got, want)
+	}
+	if got, want := scaled.RGBAAt(1, 0), (color.RGBA{255, 0, 0, 255}); got != want {
+		t.Errorf("right pixel is %v, want %v", got, want)
+	}
+}
+
+func TestScaleImageSizes(t *testing.T) {
+	img := image.NewRGBA(image.Rect(10, 20, 13, 21))
+	img.SetRGBA(12, 20, color.RGBA{0, 0, 255, 255})
+
+	tests := []struct {
+		size Vector
+		want image.Rectangle
+	}{
+		// images are never empty
+		{Vector{0, 0}, image.Rect(0, 0, 1, 1)},
+		{Vector{-3, 1}, image.Rect(0, 0, 1, 1)},
+		// scaling up repeats pixels
+		{Vector{6, 2}, image.Rect(0, 0, 6, 2)},
+	}
+
+	for _, test := range tests {
+		scaled := scaleImage(img, test.size)
+		if scaled.Rect != test.want {
+			t.Errorf("scaled to %v for %v, want %v", scaled.Rect, test.size, test.want)
+		}
+	}
+
+	// the bounds of the source do not have to start at 0, 0
+	scaled := scaleImage(img, Vector{6, 2})
+	if got, want := scaled.RGBAAt(5, 1), (color.RGBA{0, 0, 255, 255}); got != want {
+		t.Errorf("last pixel is %v, want %v", got, want)
+	}
+}
+
+func TestPruneDesktopImages(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	dir := getDesktopImagesPath()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	for _, name := range []string{"1", "2: web", "a/b", "gone"} {
+		if err := saveDesktopImage(name, img); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// other files are left alone
+	other := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(other, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := pruneDesktopImages([]Workspace{{Name: "1"}, {Name: "2: web"}, {Name: "a/b"}, {Name: "new"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	want := []string{"1.png", "2:%20web.png", "a%2Fb.png", "notes.txt"}
+	if len(names) != len(want) {
+		t.Fatalf("left %q, want %q", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("left %q, want %q", names, want)
+			break
+		}
+	}
+}
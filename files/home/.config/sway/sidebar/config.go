@@ -0,0 +1,236 @@
+package main
+
+/*
+##############################################################
+# Section: Imports
+##############################################################
+*/
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+######################################################################################################
+######################################################################################################
+## Chapter: Configuration																			##
+######################################################################################################
+######################################################################################################
+*/
+
+/*
+##############################################################
+# Section: Constants & Fields
+##############################################################
+*/
+
+// The config file in the config directory of the sidebar.
+// It uses the desktop entry syntax: the keys in [Sidebar] apply to every window,
+// the ones in [Window <name>] (e.g. [Window run]) only to that window.
+// Besides the keys below, it can contain every key of theme files, changing the selected theme.
+const CONFIG_FILE_NAME = "sidebar.conf"
+
+const (
+	CONFIG_GROUP         = "Sidebar"
+	CONFIG_WINDOW_PREFIX = "Window "
+	CONFIG_THEME_KEY     = "Theme" // the name of the theme
+)
+
+// The windows that can have their own group (all panels shown with `sidebar open` share [Window open])
+var config_windows = []string{"power", "run", "desktop", "open"}
+
+// Sets a setting from the (raw) value of its key
+type configSetter func(value string) error
+
+// The keys of the config file, besides Theme and the keys of theme files (see Theme.Set)
+var config_keys = map[string]configSetter{
+	// the windows take 1/ScreenFraction of the screen width
+	"ScreenFraction": func(value string) error {
+		return parsePositiveInt(value, &SCREEN_FRACTION)
+	},
+
+	// the number of workspaces the desktop window shows without a connection to sway
+	"Desktops": func(value string) error {
+		return parsePositiveInt(value, &DESKTOP_COUNT)
+	},
+
+	// directories with desktop files, separated by ; (the first ones take precedence)
+	"SearchDirs": func(value string) error {
+		dirs := splitDesktopList(value)
+		if len(dirs) == 0 {
+			return fmt.Errorf("expected at least one directory")
+		}
+
+		for i, dir := range dirs {
+			dir = expandHomePath(dir)
+			if !filepath.IsAbs(dir) {
+				return fmt.Errorf("%q is not an absolute path", dir)
+			}
+			dirs[i] = dir
+		}
+
+		desktop_file_paths = dirs
+		return nil
+	},
+
+	// after this many days, a launch counts half as much when sorting the results
+	"FrecencyHalfLife": func(value string) error {
+		days, err := strconv.ParseFloat(value, 64)
+		// also catches NaN and days that do not fit into a time.Duration
+		if err != nil || !(days > 0) || days*float64(24*time.Hour) >= math.MaxInt64 {
+			return fmt.Errorf("%q is not a positive number (of at most %d days)", value, int64(math.MaxInt64/(24*time.Hour)))
+		}
+
+		FRECENCY_HALF_LIFE = time.Duration(days * float64(24*time.Hour))
+		return nil
+	},
+
+	// the terminal programs with Terminal=true run in, quoted like Exec of desktop files
+	"Terminal": func(value string) error {
+		command, err := splitExec(value)
+		if err != nil {
+			return err
+		}
+		if len(command) == 0 {
+			return fmt.Errorf("expected a command")
+		}
+
+		TERMINAL_COMMAND = command
+		return nil
+	},
+}
+
+/*
+##############################################################
+# Section: Loading
+##############################################################
+*/
+
+// Gets the path of the config file
+func getConfigFilePath() (path string, err error) {
+	config_dir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(config_dir, CONFIG_FILE_NAME), nil
+}
+
+// Reads the config file and applies it for the given window.
+// If theme is not empty, it replaces the theme selected in the config file (see the --theme flag).
+// Settings that are missing or invalid keep their defaults, all problems are returned together.
+func loadConfig(window string, theme string) (err error) {
+	path, err := getConfigFilePath()
+	if err != nil {
+		return err
+	}
+
+	var errs DesktopEntryErrors
+	report := func(line int, format string, args ...interface{}) {
+		errs = append(errs, DesktopEntryError{File: path, Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// without a config file, everything keeps its default
+	config := &DesktopEntry{}
+
+	file, err := os.Open(path)
+	if err == nil {
+		config, err = parseKeyFile(file)
+		file.Close()
+
+		if parse_errs, ok := err.(DesktopEntryErrors); ok {
+			for _, parse_err := range parse_errs {
+				parse_err.File = path
+				errs = append(errs, parse_err)
+			}
+		} else if err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	// check all groups, even those of other windows
+	for _, group := range config.Groups {
+		if group.Name != CONFIG_GROUP && !strings.HasPrefix(group.Name, CONFIG_WINDOW_PREFIX) {
+			report(group.Line, "unknown group [%s], expected [%s] or [%s<%s>]", group.Name, CONFIG_GROUP, CONFIG_WINDOW_PREFIX, strings.Join(config_windows, "|"))
+			continue
+		}
+
+		if name := strings.TrimPrefix(group.Name, CONFIG_WINDOW_PREFIX); group.Name != CONFIG_GROUP && !containsString(config_windows, name) {
+			report(group.Line, "unknown window %q, expected one of %s", name, strings.Join(config_windows, ", "))
+			continue
+		}
+
+		for _, key := range group.Keys() {
+			if _, ok := config_keys[key]; !ok && key != CONFIG_THEME_KEY && !isThemeKey(key) {
+				report(group.values[key].line, "unknown key %q in [%s]", key, group.Name)
+			}
+		}
+	}
+
+	// the window specific values override the general ones
+	groups := []*DesktopGroup{}
+	for _, name := range []string{CONFIG_GROUP, CONFIG_WINDOW_PREFIX + window} {
+		if group := config.Group(name); group != nil {
+			groups = append(groups, group)
+		}
+	}
+
+	// the theme comes first, the other keys may change parts of it
+	theme_line := 0
+	if theme == "" {
+		theme = DEFAULT_THEME
+		for _, group := range groups {
+			if group.Has(CONFIG_THEME_KEY) {
+				theme = group.String(CONFIG_THEME_KEY)
+				theme_line = group.values[CONFIG_THEME_KEY].line
+			}
+		}
+	}
+
+	current_theme, err = loadTheme(theme)
+	if theme_errs, ok := err.(DesktopEntryErrors); ok {
+		errs = append(errs, theme_errs...)
+	} else if err != nil {
+		// line 0 if it came from the --theme flag
+		report(theme_line, "%v", err)
+	}
+
+	for _, group := range groups {
+		for _, key := range group.Keys() {
+			value := group.String(key)
+
+			if setter, ok := config_keys[key]; ok {
+				err = setter(value)
+			} else if _, err = current_theme.Set(key, value); err == nil {
+				continue
+			}
+
+			if err != nil {
+				report(group.values[key].line, "invalid value for %s: %v", key, err)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Writes files into the config directory of a temporary $XDG_CONFIG_HOME
+// and restores everything the config file changes afterwards
+func useTestConfig(t *testing.T, files map[string]string) (config_dir string) {
+	t.Helper()
+
+	config_home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config_home)
+	config_dir = filepath.Join(config_home, SIDEBAR_SUBDIR)
+	writeTestFiles(t, config_dir, files)
+
+	old_fraction, old_desktops, old_paths := SCREEN_FRACTION, DESKTOP_COUNT, desktop_file_paths
+	old_half_life, old_terminal, old_theme := FRECENCY_HALF_LIFE, TERMINAL_COMMAND, current_theme
+	t.Cleanup(func() {
+		SCREEN_FRACTION, DESKTOP_COUNT, desktop_file_paths = old_fraction, old_desktops, old_paths
+		FRECENCY_HALF_LIFE, TERMINAL_COMMAND, current_theme = old_half_life, old_terminal, old_theme
+	})
+
+	return config_dir
+}
+
+// An error expected on a line, message is a part of its message
+type expectedError struct {
+	line    int
+	message string
+}
+
+// Checks that err is DesktopEntryErrors with exactly the expected errors, in order
+func checkErrors(t *testing.T, err error, file string, want []expectedError) {
+	t.Helper()
+
+	if len(want) == 0 {
+		if err != nil {
+			t.Errorf("unexpected errors:\n%v", err)
+		}
+		return
+	}
+
+	errs, ok := err.(DesktopEntryErrors)
+	if !ok {
+		t.Fatalf("got %v, want DesktopEntryErrors", err)
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d:\n%v", len(errs), len(want), errs)
+	}
+
+	for i, err := range errs {
+		if err.Line != want[i].line || !strings.Contains(err.Message, want[i].message) || filepath.Base(err.File) != file {
+			t.Errorf("error %d is %q, want %s:%d: ...%s...", i, err.Error(), file, want[i].line, want[i].message)
+		}
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	useTestConfig(t, nil)
+
+	SCREEN_FRACTION, DESKTOP_COUNT = 4, 6
+
+	// without a config file, everything keeps its default
+	err := loadConfig("run", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if SCREEN_FRACTION != 4 || DESKTOP_COUNT != 6 || !reflect.DeepEqual(current_theme, builtin_themes[DEFAULT_THEME]) {
+		t.Errorf("the defaults changed to %d, %d, %q", SCREEN_FRACTION, DESKTOP_COUNT, current_theme.Name)
+	}
+}
+
+func TestLoadConfigWindowGroups(t *testing.T) {
+	useTestConfig(t, map[string]string{CONFIG_FILE_NAME: `[Sidebar]
+ScreenFraction=5
+Desktops=8
+Accent=#00ff00
+
+[Window run]
+ScreenFraction=3
+Theme=light
+
+[Window desktop]
+Desktops=4
+`})
+
+	tests := []struct {
+		window    string
+		fraction  int
+		desktops  int
+		theme     string
+		accent    uint32
+		wantTheme Theme
+	}{
+		// the window group overrides [Sidebar], no matter where the keys are
+		{"run", 3, 8, "light", 0x00ff00, builtin_themes["light"]},
+		{"desktop", 5, 4, "dark", 0x00ff00, builtin_themes["dark"]},
+		{"power", 5, 8, "dark", 0x00ff00, builtin_themes["dark"]},
+	}
+
+	for _, test := range tests {
+		SCREEN_FRACTION, DESKTOP_COUNT = 4, 6
+
+		err := loadConfig(test.window, "")
+		if err != nil {
+			t.Fatalf("%s: %v", test.window, err)
+		}
+
+		if SCREEN_FRACTION != test.fraction || DESKTOP_COUNT != test.desktops {
+			t.Errorf("%s: ScreenFraction=%d, Desktops=%d, want %d, %d", test.window, SCREEN_FRACTION, DESKTOP_COUNT, test.fraction, test.desktops)
+		}
+
+		// the theme keys change the selected theme
+		if current_theme.Name != test.theme || ACCENT.RGB() != test.accent || BACKGROUND.RGB() != test.wantTheme.Colors[BACKGROUND] {
+			t.Errorf("%s: theme %q with accent %06x and background %06x", test.window, current_theme.Name, ACCENT.RGB(), BACKGROUND.RGB())
+		}
+	}
+
+	// the --theme flag wins over the config file
+	if err := loadConfig("run", "dark"); err != nil || current_theme.Name != "dark" || ACCENT.RGB() != 0x00ff00 {
+		t.Errorf("got theme %q with accent %06x, %v", current_theme.Name, ACCENT.RGB(), err)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	useTestConfig(t, map[string]string{CONFIG_FILE_NAME: `[Sidebar]
+ScreenFraction=0
+Colour=#ffffff
+HeaderSize=big
+SearchDirs=relative/dir
+
+[Window nope]
+Desktops=2
+
+[Other]
+Foo=bar
+
+[Window power]
+Unknown=1
+Desktops=-2
+junk line
+`})
+
+	SCREEN_FRACTION, DESKTOP_COUNT = 4, 6
+	old_paths := desktop_file_paths
+
+	err := loadConfig("power", "")
+
+	// all groups are checked, even those of other windows, then the values of this window
+	checkErrors(t, err, CONFIG_FILE_NAME, []expectedError{
+		{16, "expected key=value"},
+		{3, `unknown key "Colour" in [Sidebar]`},
+		{7, `unknown window "nope"`},
+		{10, "unknown group [Other]"},
+		{14, `unknown key "Unknown" in [Window power]`},
+		{2, "invalid value for ScreenFraction"},
+		{4, "invalid value for HeaderSize"},
+		{5, "invalid value for SearchDirs"},
+		{15, "invalid value for Desktops"},
+	})
+
+	// invalid values keep their defaults
+	if SCREEN_FRACTION != 4 || DESKTOP_COUNT != 6 || !reflect.DeepEqual(desktop_file_paths, old_paths) {
+		t.Errorf("invalid values changed the settings to %d, %d, %q", SCREEN_FRACTION, DESKTOP_COUNT, desktop_file_paths)
+	}
+	if current_theme.Fonts[HEADER_FONT] != builtin_themes[DEFAULT_THEME].Fonts[HEADER_FONT] {
+		t.Errorf("an invalid size changed the header font to %+v", current_theme.Fonts[HEADER_FONT])
+	}
+}
+
+func TestLoadConfigUnknownTheme(t *testing.T) {
+	useTestConfig(t, map[string]string{CONFIG_FILE_NAME: "[Sidebar]\nTheme=missing\n"})
+
+	err := loadConfig("run", "")
+	checkErrors(t, err, CONFIG_FILE_NAME, []expectedError{{2, `unknown theme "missing"`}})
+	if current_theme.Name != DEFAULT_THEME {
+		t.Errorf("got theme %q instead of the default", current_theme.Name)
+	}
+
+	// from the flag, the error has no line
+	err = loadConfig("run", "missing-too")
+	checkErrors(t, err, CONFIG_FILE_NAME, []expectedError{{0, `unknown theme "missing-too"`}})
+}
+
+func TestConfigKeys(t *testing.T) {
+	useTestConfig(t, map[string]string{CONFIG_FILE_NAME: `[Sidebar]
+SearchDirs=~/apps;/usr/share/applications/;
+FrecencyHalfLife=14
+Terminal=foot --title "a terminal" -e
+`})
+	t.Setenv("HOME", "/home/test")
+
+	if err := loadConfig("run", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"/home/test/apps", "/usr/share/applications/"}; !reflect.DeepEqual(desktop_file_paths, want) {
+		t.Errorf("SearchDirs is %q, want %q", desktop_file_paths, want)
+	}
+	if FRECENCY_HALF_LIFE != 14*24*time.Hour {
+		t.Errorf("FrecencyHalfLife is %v", FRECENCY_HALF_LIFE)
+	}
+	if want := []string{"foot", "--title", "a terminal", "-e"}; !reflect.DeepEqual(TERMINAL_COMMAND, want) {
+		t.Errorf("Terminal is %q, want %q", TERMINAL_COMMAND, want)
+	}
+}
@@ -0,0 +1,591 @@
+package main
+
+/*
+##############################################################
+# Section: Imports
+##############################################################
+*/
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+######################################################################################################
+######################################################################################################
+## Chapter: Desktop Entries																			##
+######################################################################################################
+######################################################################################################
+*/
+
+// Implements https://specifications.freedesktop.org/desktop-entry-spec/latest/
+
+/*
+##############################################################
+# Section: Types
+##############################################################
+*/
+
+const DESKTOP_ENTRY_GROUP = "Desktop Entry"
+const DESKTOP_ACTION_PREFIX = "Desktop Action "
+
+// A parsed .desktop file
+type DesktopEntry struct {
+	// the path the entry was read from (if any)
+	File string
+
+	// the groups in the order they appear in the file
+	Groups []*DesktopGroup
+}
+
+// A [group] of a desktop entry and its key-value pairs
+type DesktopGroup struct {
+	Name string
+	Line int
+
+	// keys (including the locale, e.g. "Name[de]") in file order
+	keys   []string
+	values map[string]desktopValue
+}
+
+type desktopValue struct {
+	raw  string
+	line int
+}
+
+// A problem found in a desktop entry
+type DesktopEntryError struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (err DesktopEntryError) Error() string {
+	// errors that do not belong to a line
+	if err.Line == 0 {
+		return strings.TrimPrefix(err.File+": "+err.Message, ": ")
+	}
+
+	if err.File == "" {
+		return fmt.Sprintf("line %d: %s", err.Line, err.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", err.File, err.Line, err.Message)
+}
+
+// All problems found in a desktop entry
+type DesktopEntryErrors []DesktopEntryError
+
+func (errs DesktopEntryErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+/*
+##############################################################
+# Section: Parsing
+##############################################################
+*/
+
+var desktop_group_regex = regexp.MustCompile(`^\[([^\[\]\x00-\x1f\x7f]+)\]$`)
+var desktop_key_regex = regexp.MustCompile(`^([A-Za-z0-9-]+)(\[([A-Za-z]+(_[A-Za-z]+)?(\.[A-Za-z0-9-]+)?(@[A-Za-z]+)?)\])?$`)
+
+// Parses a desktop file
+func parseDesktopFile(path string) (entry *DesktopEntry, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entry, err = ParseDesktopEntry(file)
+	entry.File = path
+
+	// add the file name to the errors
+	if errs, ok := err.(DesktopEntryErrors); ok {
+		for i := range errs {
+			errs[i].File = path
+		}
+	}
+
+	return entry, err
+}
+
+// Parses a desktop entry.
+// Invalid lines are skipped and reported in the returned DesktopEntryErrors,
+// so the entry can still be used if the caller does not mind.
+func ParseDesktopEntry(reader io.Reader) (entry *DesktopEntry, err error) {
+	entry, err = parseKeyFile(reader)
+
+	errs, ok := err.(DesktopEntryErrors)
+	if err != nil && !ok {
+		return entry, err
+	}
+
+	if len(entry.Groups) > 0 && entry.Groups[0].Name != DESKTOP_ENTRY_GROUP {
+		errs = append(errs, DesktopEntryError{Line: entry.Groups[0].Line,
+			Message: fmt.Sprintf("first group must be %q, not %q", DESKTOP_ENTRY_GROUP, entry.Groups[0].Name)})
+	}
+
+	if verr, ok := entry.Validate().(DesktopEntryErrors); ok {
+		errs = append(errs, verr...)
+	}
+
+	if len(errs) > 0 {
+		return entry, errs
+	}
+
+	return entry, nil
+}
+
+// Parses the syntax shared by desktop entries and other freedesktop files (like index.theme)
+// without checking for any specific groups or keys
+func parseKeyFile(reader io.Reader) (file *DesktopEntry, err error) {
+	file = &DesktopEntry{}
+
+	var errs DesktopEntryErrors
+	report := func(line int, format string, args ...interface{}) {
+		errs = append(errs, DesktopEntryError{Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var group *DesktopGroup
+
+	scanner := bufio.NewScanner(reader)
+	for number := 1; scanner.Scan(); number++ {
+		line := scanner.Text()
+
+		if !utf8.ValidString(line) {
+			report(number, "invalid UTF-8")
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+
+		// blank lines and comments
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		// group headers
+		if strings.HasPrefix(trimmed, "[") {
+			match := desktop_group_regex.FindStringSubmatch(trimmed)
+			if match == nil {
+				report(number, "invalid group header %q", trimmed)
+				group = nil
+				continue
+			}
+
+			if file.Group(match[1]) != nil {
+				report(number, "duplicate group %q", match[1])
+				group = nil
+				continue
+			}
+
+			group = &DesktopGroup{Name: match[1], Line: number, values: make(map[string]desktopValue)}
+			file.Groups = append(file.Groups, group)
+			continue
+		}
+
+		// key-value pairs
+		separator := strings.Index(line, "=")
+		if separator < 0 {
+			report(number, "expected key=value, group header or comment")
+			continue
+		}
+
+		if group == nil {
+			report(number, "entry outside of a valid group")
+			continue
+		}
+
+		key := strings.TrimSpace(line[:separator])
+		value := strings.TrimSpace(line[separator+1:])
+
+		if !desktop_key_regex.MatchString(key) {
+			report(number, "invalid key %q", key)
+			continue
+		}
+
+		if _, ok := group.values[key]; ok {
+			report(number, "duplicate key %q in group %q", key, group.Name)
+			continue
+		}
+
+		group.keys = append(group.keys, key)
+		group.values[key] = desktopValue{raw: value, line: number}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return file, err
+	}
+
+	if len(errs) > 0 {
+		return file, errs
+	}
+
+	return file, nil
+}
+
+// Checks the entry for missing or invalid required keys
+func (entry *DesktopEntry) Validate() (err error) {
+	var errs DesktopEntryErrors
+
+	main := entry.Main()
+	if main == nil {
+		errs = append(errs, DesktopEntryError{File: entry.File, Line: 1, Message: "missing group \"" + DESKTOP_ENTRY_GROUP + "\""})
+		return errs
+	}
+
+	require := func(group *DesktopGroup, key string) {
+		if !group.Has(key) {
+			errs = append(errs, DesktopEntryError{File: entry.File, Line: group.Line,
+				Message: fmt.Sprintf("group %q is missing required key %q", group.Name, key)})
+		}
+	}
+
+	require(main, "Type")
+	require(main, "Name")
+
+	switch main.String("Type") {
+	case "Application", "Directory", "":
+	case "Link":
+		require(main, "URL")
+	default:
+		// unknown types are allowed by the spec, they are just not ours to handle
+	}
+
+	// check the typed keys
+	for _, group := range entry.Groups {
+		for _, key := range group.keys {
+			value := group.values[key]
+			switch key {
+			case "NoDisplay", "Hidden", "DBusActivatable", "Terminal", "StartupNotify", "PrefersNonDefaultGPU", "SingleMainWindow":
+				if value.raw != "true" && value.raw != "false" {
+					errs = append(errs, DesktopEntryError{File: entry.File, Line: value.line,
+						Message: fmt.Sprintf("%s must be true or false, not %q", key, value.raw)})
+				}
+			}
+		}
+	}
+
+	// every listed action needs its group
+	for _, id := range main.List("Actions") {
+		action := entry.Group(DESKTOP_ACTION_PREFIX + id)
+		if action == nil {
+			errs = append(errs, DesktopEntryError{File: entry.File, Line: main.values["Actions"].line,
+				Message: fmt.Sprintf("action %q has no group", id)})
+			continue
+		}
+		require(action, "Name")
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+/*
+##############################################################
+# Section: Groups
+##############################################################
+*/
+
+// Gets a group by name, nil if there is none
+func (entry *DesktopEntry) Group(name string) (group *DesktopGroup) {
+	for _, group := range entry.Groups {
+		if group.Name == name {
+			return group
+		}
+	}
+	return nil
+}
+
+// Gets the [Desktop Entry] group
+func (entry *DesktopEntry) Main() (group *DesktopGroup) {
+	return entry.Group(DESKTOP_ENTRY_GROUP)
+}
+
+// Gets the [Desktop Action <id>] groups listed in the Actions key
+func (entry *DesktopEntry) Actions() (actions []*DesktopGroup) {
+	main := entry.Main()
+	if main == nil {
+		return nil
+	}
+
+	for _, id := range main.List("Actions") {
+		if action := entry.Group(DESKTOP_ACTION_PREFIX + id); action != nil {
+			actions = append(actions, action)
+		}
+	}
+
+	return actions
+}
+
+// Gets all keys of the group (including localized ones) in file order
+func (group *DesktopGroup) Keys() (keys []string) {
+	return append([]string(nil), group.keys...)
+}
+
+func (group *DesktopGroup) Has(key string) bool {
+	_, ok := group.values[key]
+	return ok
+}
+
+// Gets the value as written in the file
+func (group *DesktopGroup) Raw(key string) (value string, ok bool) {
+	v, ok := group.values[key]
+	return v.raw, ok
+}
+
+// Gets a string value with escape sequences resolved
+func (group *DesktopGroup) String(key string) (value string) {
+	raw, _ := group.Raw(key)
+	return unescapeDesktopValue(raw, false)
+}
+
+// Gets a localestring value for the given locale (e.g. de_DE.UTF-8@euro)
+func (group *DesktopGroup) LocaleString(key string, locale string) (value string) {
+	return group.String(group.localizedKey(key, locale))
+}
+
+// Gets a boolean value. Missing keys are false.
+func (group *DesktopGroup) Bool(key string) (value bool, err error) {
+	raw, ok := group.Raw(key)
+	if !ok {
+		return false, nil
+	}
+
+	switch raw {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+
+	return false, DesktopEntryError{Line: group.values[key].line, Message: fmt.Sprintf("%s must be true or false, not %q", key, raw)}
+}
+
+// Gets a numeric value
+func (group *DesktopGroup) Number(key string) (value float64, err error) {
+	raw, ok := group.Raw(key)
+	if !ok {
+		return 0, nil
+	}
+
+	value, err = strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, DesktopEntryError{Line: group.values[key].line, Message: fmt.Sprintf("%s is not a number: %q", key, raw)}
+	}
+	return value, nil
+}
+
+// Gets a list value (separated by ;)
+func (group *DesktopGroup) List(key string) (values []string) {
+	raw, _ := group.Raw(key)
+	return splitDesktopList(raw)
+}
+
+// Gets a localized list value for the given locale
+func (group *DesktopGroup) LocaleList(key string, locale string) (values []string) {
+	return group.List(group.localizedKey(key, locale))
+}
+
+// Finds the best matching key for the locale, following the order of the spec:
+// lang_COUNTRY@MODIFIER, lang_COUNTRY, lang@MODIFIER, lang, unlocalized
+func (group *DesktopGroup) localizedKey(key string, locale string) (localized string) {
+	lang, country, modifier := splitLocale(locale)
+	if lang == "" {
+		return key
+	}
+
+	var candidates []string
+	if country != "" && modifier != "" {
+		candidates = append(candidates, lang+"_"+country+"@"+modifier)
+	}
+	if country != "" {
+		candidates = append(candidates, lang+"_"+country)
+	}
+	if modifier != "" {
+		candidates = append(candidates, lang+"@"+modifier)
+	}
+	candidates = append(candidates, lang)
+
+	for _, candidate := range candidates {
+		if group.Has(key + "[" + candidate + "]") {
+			return key + "[" + candidate + "]"
+		}
+	}
+
+	return key
+}
+
+/*
+##############################################################
+# Section: Common keys
+##############################################################
+*/
+
+// These use the locale of the user and the [Desktop Entry] group
+
+func (entry *DesktopEntry) localeString(key string) string {
+	if main := entry.Main(); main != nil {
+		return main.LocaleString(key, getLocale())
+	}
+	return ""
+}
+
+func (entry *DesktopEntry) string(key string) string {
+	if main := entry.Main(); main != nil {
+		return main.String(key)
+	}
+	return ""
+}
+
+func (entry *DesktopEntry) bool(key string) bool {
+	if main := entry.Main(); main != nil {
+		value, _ := main.Bool(key)
+		return value
+	}
+	return false
+}
+
+func (entry *DesktopEntry) list(key string) []string {
+	if main := entry.Main(); main != nil {
+		return main.List(key)
+	}
+	return nil
+}
+
+func (entry *DesktopEntry) Type() string        { return entry.string("Type") }
+func (entry *DesktopEntry) Name() string        { return entry.localeString("Name") }
+func (entry *DesktopEntry) GenericName() string { return entry.localeString("GenericName") }
+func (entry *DesktopEntry) Comment() string     { return entry.localeString("Comment") }
+func (entry *DesktopEntry) Icon() string        { return entry.localeString("Icon") }
+func (entry *DesktopEntry) Exec() string        { return entry.string("Exec") }
+func (entry *DesktopEntry) TryExec() string     { return entry.string("TryExec") }
+func (entry *DesktopEntry) Path() string        { return entry.string("Path") }
+func (entry *DesktopEntry) URL() string         { return entry.string("URL") }
+func (entry *DesktopEntry) NoDisplay() bool     { return entry.bool("NoDisplay") }
+func (entry *DesktopEntry) Hidden() bool        { return entry.bool("Hidden") }
+func (entry *DesktopEntry) Terminal() bool      { return entry.bool("Terminal") }
+func (entry *DesktopEntry) Categories() []string {
+	return entry.list("Categories")
+}
+
+func (entry *DesktopEntry) Keywords() []string {
+	if main := entry.Main(); main != nil {
+		return main.LocaleList("Keywords", getLocale())
+	}
+	return nil
+}
+
+/*
+##############################################################
+# Section: Values & Locales
+##############################################################
+*/
+
+// Resolves the escape sequences \s, \n, \t, \r and \\ (and \; inside of lists)
+func unescapeDesktopValue(raw string, list bool) (value string) {
+	if !strings.Contains(raw, "\\") {
+		return raw
+	}
+
+	var builder strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' || i+1 == len(raw) {
+			builder.WriteByte(raw[i])
+			continue
+		}
+
+		i++
+		switch raw[i] {
+		case 's':
+			builder.WriteByte(' ')
+		case 'n':
+			builder.WriteByte('\n')
+		case 't':
+			builder.WriteByte('\t')
+		case 'r':
+			builder.WriteByte('\r')
+		case '\\':
+			builder.WriteByte('\\')
+		case ';':
+			if list {
+				builder.WriteByte(';')
+			} else {
+				builder.WriteString("\\;")
+			}
+		default:
+			// unknown escapes are kept as they are
+			builder.WriteByte('\\')
+			builder.WriteByte(raw[i])
+		}
+	}
+
+	return builder.String()
+}
+
+// Splits a list at every unescaped ; and unescapes the elements
+func splitDesktopList(raw string) (values []string) {
+	start := 0
+	for i := 0; i < len(raw); i++ {
+		switch raw[i] {
+		case '\\':
+			// skip the escaped character
+			i++
+		case ';':
+			values = append(values, unescapeDesktopValue(raw[start:i], true))
+			start = i + 1
+		}
+	}
+
+	// the trailing ; is optional
+	if start < len(raw) {
+		values = append(values, unescapeDesktopValue(raw[start:], true))
+	}
+
+	return values
+}
+
+// Splits a locale of the form lang_COUNTRY.ENCODING@MODIFIER.
+// The encoding is ignored, as the spec demands.
+func splitLocale(locale string) (lang string, country string, modifier string) {
+	if at := strings.Index(locale, "@"); at >= 0 {
+		locale, modifier = locale[:at], locale[at+1:]
+	}
+	if dot := strings.Index(locale, "."); dot >= 0 {
+		locale = locale[:dot]
+	}
+	if underscore := strings.Index(locale, "_"); underscore >= 0 {
+		locale, country = locale[:underscore], locale[underscore+1:]
+	}
+	return locale, country, modifier
+}
+
+// Gets the locale used for messages from the environment
+func getLocale() (locale string) {
+	for _, variable := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		locale = os.Getenv(variable)
+		if locale != "" {
+			break
+		}
+	}
+
+	// these mean "no localization"
+	if locale == "C" || locale == "POSIX" || strings.HasPrefix(locale, "C.") {
+		return ""
+	}
+
+	return locale
+}
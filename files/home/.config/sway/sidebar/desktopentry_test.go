@@ -0,0 +1,298 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Parses a desktop entry that has to be valid
+func parseTestEntry(t *testing.T, text string) (entry *DesktopEntry) {
+	t.Helper()
+
+	entry, err := ParseDesktopEntry(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entry
+}
+
+func TestDesktopEntryActionGroups(t *testing.T) {
+	entry := parseTestEntry(t, `[Desktop Entry]
+Type=Application
+Name=Files
+Exec=nautilus %U
+Actions=new-window;private;
+
+[Desktop Action new-window]
+Name=New Window
+Exec=nautilus --new-window
+
+[Desktop Action private]
+Name=Private Window
+Exec=nautilus --private
+`)
+
+	// the keys of the actions are theirs, not the entry's
+	if entry.Name() != "Files" || entry.Exec() != "nautilus %U" {
+		t.Errorf("the entry is %q with %q", entry.Name(), entry.Exec())
+	}
+
+	actions := entry.Actions()
+	if len(actions) != 2 {
+		t.Fatalf("got %d actions, want 2", len(actions))
+	}
+
+	want := []struct{ name, exec string }{
+		{"New Window", "nautilus --new-window"},
+		{"Private Window", "nautilus --private"},
+	}
+	for i, action := range actions {
+		if action.String("Name") != want[i].name || action.String("Exec") != want[i].exec {
+			t.Errorf("action %d is %q with %q, want %q with %q", i,
+				action.String("Name"), action.String("Exec"), want[i].name, want[i].exec)
+		}
+	}
+}
+
+func TestDesktopEntryUnlistedActions(t *testing.T) {
+	entry := parseTestEntry(t, `[Desktop Entry]
+Type=Application
+Name=Files
+Actions=new-window;
+
+[Desktop Action new-window]
+Name=New Window
+
+[Desktop Action unlisted]
+Name=Unlisted
+`)
+
+	// only the listed actions count
+	if actions := entry.Actions(); len(actions) != 1 || actions[0].Name != "Desktop Action new-window" {
+		t.Errorf("got actions %v", actions)
+	}
+}
+
+func TestDesktopGroupLocaleString(t *testing.T) {
+	entry := parseTestEntry(t, `[Desktop Entry]
+Type=Application
+Name=Files
+Name[de]=Dateien
+Name[de_CH]=Dateie
+Name[de@euro]=Euro-Dateien
+Name[sr_RS@latin]=Datoteke
+Keywords=folder;explorer;
+Keywords[de]=Ordner;Explorer;
+`)
+	main := entry.Main()
+
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		// lang_COUNTRY@MODIFIER, lang_COUNTRY, lang@MODIFIER, lang, unlocalized
+		{"sr_RS@latin", "Datoteke"},
+		{"de_CH", "Dateie"},
+		{"de_CH@euro", "Dateie"},
+		{"de_AT@euro", "Euro-Dateien"},
+		{"de_AT", "Dateien"},
+		{"de", "Dateien"},
+		{"sr_RS", "Files"},
+		{"fr_FR", "Files"},
+		{"", "Files"},
+
+		// the encoding is ignored
+		{"de_CH.UTF-8", "Dateie"},
+		{"de_AT.ISO-8859-15@euro", "Euro-Dateien"},
+	}
+
+	for _, test := range tests {
+		if got := main.LocaleString("Name", test.locale); got != test.want {
+			t.Errorf("Name for %q is %q, want %q", test.locale, got, test.want)
+		}
+	}
+
+	if got := main.LocaleList("Keywords", "de_DE.UTF-8"); strings.Join(got, ",") != "Ordner,Explorer" {
+		t.Errorf("Keywords for de_DE are %q", got)
+	}
+	if got := main.LocaleList("Keywords", "fr"); strings.Join(got, ",") != "folder,explorer" {
+		t.Errorf("Keywords for fr are %q", got)
+	}
+}
+
+func TestDesktopEntryUsesLocale(t *testing.T) {
+	entry := parseTestEntry(t, "[Desktop Entry]\nType=Application\nName=Files\nName[de]=Dateien\n")
+
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "de_DE.UTF-8")
+	t.Setenv("LANG", "fr_FR.UTF-8")
+	if entry.Name() != "Dateien" {
+		t.Errorf("Name with LC_MESSAGES=de_DE is %q", entry.Name())
+	}
+
+	// C means no translations, even with LANG set
+	t.Setenv("LC_ALL", "C.UTF-8")
+	if entry.Name() != "Files" {
+		t.Errorf("Name with LC_ALL=C.UTF-8 is %q", entry.Name())
+	}
+}
+
+func TestUnescapeDesktopValue(t *testing.T) {
+	tests := []struct {
+		raw  string
+		list bool
+		want string
+	}{
+		{`plain`, false, "plain"},
+		{`a\sb`, false, "a b"},
+		{`line\nline`, false, "line\nline"},
+		{`tab\tcr\r`, false, "tab\tcr\r"},
+		{`back\\slash`, false, `back\slash`},
+		{`\\s`, false, `\s`},
+
+		// \; only means something in lists
+		{`a\;b`, false, `a\;b`},
+		{`a\;b`, true, "a;b"},
+
+		// unknown escapes and a trailing backslash are kept
+		{`C:\x`, false, `C:\x`},
+		{`end\`, false, `end\`},
+	}
+
+	for _, test := range tests {
+		if got := unescapeDesktopValue(test.raw, test.list); got != test.want {
+			t.Errorf("unescapeDesktopValue(%q, %v) = %q, want %q", test.raw, test.list, got, test.want)
+		}
+	}
+}
+
+func TestSplitDesktopList(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{``, nil},
+		{`a`, []string{"a"}},
+		{`a;b;`, []string{"a", "b"}},
+		{`a;b`, []string{"a", "b"}},
+		{`a;;b`, []string{"a", "", "b"}},
+		{`a\;b;c`, []string{"a;b", "c"}},
+		{`a\\;b`, []string{`a\`, "b"}},
+		{`x\sy;z\n`, []string{"x y", "z\n"}},
+	}
+
+	for _, test := range tests {
+		got := splitDesktopList(test.raw)
+		if len(got) != len(test.want) {
+			t.Errorf("splitDesktopList(%q) = %q, want %q", test.raw, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("splitDesktopList(%q) = %q, want %q", test.raw, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestDesktopEntryComments(t *testing.T) {
+	entry := parseTestEntry(t, `# a comment before the groups
+
+[Desktop Entry]
+  # indented comments and blank lines
+Type=Application
+
+Name = Files
+Comment=# not a comment
+`)
+
+	// spaces around the = are allowed
+	if entry.Name() != "Files" || entry.Comment() != "# not a comment" {
+		t.Errorf("the entry is %q, %q", entry.Name(), entry.Comment())
+	}
+	if keys := entry.Main().Keys(); strings.Join(keys, ",") != "Type,Name,Comment" {
+		t.Errorf("the keys are %q", keys)
+	}
+}
+
+func TestDesktopEntryErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		lines []int
+		// a part of each message
+		messages []string
+	}{
+		{"no errors", "[Desktop Entry]\nType=Application\nName=Files\n", nil, nil},
+		{"missing main group", "# nothing\n", []int{1}, []string{`missing group "Desktop Entry"`}},
+		{"other group first", "[Other]\nA=b\n[Desktop Entry]\nType=Application\nName=Files\n",
+			[]int{1}, []string{`first group must be "Desktop Entry"`}},
+		{"missing keys", "\n[Desktop Entry]\nExec=true\n",
+			[]int{2, 2}, []string{`missing required key "Type"`, `missing required key "Name"`}},
+		{"link without url", "[Desktop Entry]\nType=Link\nName=Docs\n", []int{1}, []string{`"URL"`}},
+		{"invalid lines", "[Desktop Entry]\nType=Application\nName=Files\njunk\nbad key=1\n[Broken\n",
+			[]int{4, 5, 6}, []string{"expected key=value", `invalid key "bad key"`, `invalid group header "[Broken"`}},
+		{"entry before a group", "A=b\n[Desktop Entry]\nType=Application\nName=Files\n",
+			[]int{1}, []string{"outside of a valid group"}},
+		{"duplicates", "[Desktop Entry]\nType=Application\nName=Files\nName=Other\n[Desktop Entry]\n",
+			[]int{4, 5}, []string{`duplicate key "Name"`, `duplicate group "Desktop Entry"`}},
+		{"invalid boolean", "[Desktop Entry]\nType=Application\nName=Files\nTerminal=yes\n",
+			[]int{4}, []string{"Terminal must be true or false"}},
+		{"action without group", "[Desktop Entry]\nType=Application\nName=Files\nActions=new;\n",
+			[]int{4}, []string{`action "new" has no group`}},
+		{"action without name", "[Desktop Entry]\nType=Application\nName=Files\nActions=new;\n[Desktop Action new]\nExec=x\n",
+			[]int{5}, []string{`missing required key "Name"`}},
+		{"invalid utf-8", "[Desktop Entry]\nType=Application\nName=Files\nComment=\xff\n",
+			[]int{4}, []string{"invalid UTF-8"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseDesktopEntry(strings.NewReader(test.text))
+			if test.lines == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			errs, ok := err.(DesktopEntryErrors)
+			if !ok {
+				t.Fatalf("got %v, want DesktopEntryErrors", err)
+			}
+			if len(errs) != len(test.lines) {
+				t.Fatalf("got %d errors, want %d:\n%v", len(errs), len(test.lines), errs)
+			}
+
+			for i, err := range errs {
+				if err.Line != test.lines[i] || !strings.Contains(err.Message, test.messages[i]) {
+					t.Errorf("error %d is %q on line %d, want %q on line %d",
+						i, err.Message, err.Line, test.messages[i], test.lines[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseDesktopFileErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.desktop")
+	err := os.WriteFile(path, []byte("[Desktop Entry]\nType=Application\nName=Broken\njunk\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := parseDesktopFile(path)
+
+	// the entry can still be used
+	if entry == nil || entry.Name() != "Broken" || entry.File != path {
+		t.Fatalf("got entry %+v", entry)
+	}
+
+	want := path + ":4: expected key=value, group header or comment"
+	if err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %q", err, want)
+	}
+}
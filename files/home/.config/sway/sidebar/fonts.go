@@ -0,0 +1,622 @@
+package main
+
+/*
+##############################################################
+# Section: Imports
+##############################################################
+*/
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
+	"golang.org/x/image/font/sfnt"
+)
+
+/*
+######################################################################################################
+######################################################################################################
+## Chapter: Fonts																					##
+######################################################################################################
+######################################################################################################
+*/
+
+/*
+##############################################################
+# Section: Constants & Fields
+##############################################################
+*/
+
+// Used when a family can not be found at all
+const DEFAULT_FONT_FAMILY = "sans-serif"
+
+// The style of fonts without one
+const DEFAULT_FONT_STYLE = "Regular"
+
+// Where the font directories are configured when fc-match is not installed
+var FONTS_CONF_PATH = "/etc/fonts/fonts.conf"
+
+// Searched besides the directories of fonts.conf
+var default_font_dirs = []string{"/usr/share/fonts", "/usr/local/share/fonts", "~/.fonts"}
+
+var font_extensions = []string{".ttf", ".otf", ".ttc"}
+
+// A font at a size. Family can also be the path of a font file.
+type FontKey struct {
+	Family string
+	Style  string
+	Size   int
+}
+
+// The glyphs of a font
+type FontCharset interface {
+	Has(r rune) bool
+}
+
+// The glyphs of a font file, looked up in its cmap table
+type sfntCharset struct {
+	font   *sfnt.Font
+	buffer sfnt.Buffer
+}
+
+// Measures (and draws) the glyphs of an open font file.
+// Windows use sdl_ttf (*ttf.Font), images drawn without sdl use opentype (see ImageCanvas),
+// so the text is always measured by what draws it.
+type FontFace interface {
+	SizeUTF8(text string) (w int, h int, err error)
+
+	// the height of a line and the part of it above the baseline
+	Height() int
+	Ascent() int
+
+	Close()
+}
+
+// Opens a font file at a size (in pixels). See useImageFonts.
+var openFontFace = openTTFFace
+
+// An open font, with the fonts used for the glyphs it does not have
+type LoadedFont struct {
+	key  FontKey
+	path string
+	face FontFace
+
+	// the glyphs of the font (nil if unknown, then it is assumed to have all of them)
+	charset FontCharset
+
+	// the font used for a missing glyph (nil if no font has it)
+	fallbacks map[rune]*LoadedFont
+
+	// the different fonts of fallbacks, in the order they were found
+	fallback_fonts []*LoadedFont
+}
+
+// A part of a text drawn with a single font
+type fontRun struct {
+	font *LoadedFont
+	text string
+}
+
+// All fonts stay open until closeFonts, so the items can use them on every frame
+var loaded_fonts = make(map[FontKey]*LoadedFont)
+
+// Font files by family and style ("family:style")
+var font_paths = make(map[string]string)
+
+// Glyphs by font file
+var font_charsets = make(map[string]FontCharset)
+
+// The font files in the font directories, only used without fc-match (nil until scanned)
+var font_files []string
+
+/*
+##############################################################
+# Section: Loading
+##############################################################
+*/
+
+// Gets the font of a role in the current theme. Do not close it.
+func getFont(role FontRole) (font *LoadedFont, err error) {
+	return loadFont(role.Font().Family, role.Font().Style, role.Font().Size)
+}
+
+// Gets the height of a line in the font of a role (0 if it can not be opened)
+func fontHeight(role FontRole) int32 {
+	font, err := getFont(role)
+	if err != nil {
+		return 0
+	}
+	return int32(font.Height())
+}
+
+// Gets a font by family, style and size, opening it the first time. Do not close it.
+func loadFont(family string, style string, size int) (font *LoadedFont, err error) {
+	key := FontKey{family, style, size}
+	if font, ok := loaded_fonts[key]; ok {
+		return font, nil
+	}
+
+	path, err := resolveFont(family, style)
+	if err != nil {
+		return nil, err
+	}
+
+	face, err := openFontFace(path, size)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	font = &LoadedFont{key: key, path: path, face: face, fallbacks: make(map[rune]*LoadedFont)}
+
+	// without the glyphs, there is no fallback, but the font still works
+	font.charset, err = getFontCharset(path)
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	loaded_fonts[key] = font
+	return font, nil
+}
+
+// Opens a font file with sdl_ttf
+func openTTFFace(path string, size int) (face FontFace, err error) {
+	ttf_font, err := ttf.OpenFont(path, size)
+	if err != nil {
+		return nil, err
+	}
+	return ttf_font, nil
+}
+
+// Closes all fonts
+func closeFonts() {
+	for key, font := range loaded_fonts {
+		font.face.Close()
+		delete(loaded_fonts, key)
+	}
+}
+
+/*
+##############################################################
+# Section: Resolving
+##############################################################
+*/
+
+// Finds the file of a font family, through fontconfig or the font directories of fonts.conf
+func resolveFont(family string, style string) (path string, err error) {
+	// font files are used as they are
+	if strings.Contains(family, "/") {
+		return expandHomePath(family), nil
+	}
+
+	if style == "" {
+		style = DEFAULT_FONT_STYLE
+	}
+
+	name := family + ":" + style
+	if path, ok := font_paths[name]; ok {
+		return path, nil
+	}
+
+	if _, err := exec.LookPath("fc-match"); err == nil {
+		path, err = matchFont(family + ":style=" + style)
+	} else {
+		path, err = findFontFile(family, style)
+		if err != nil {
+			// like fontconfig, rather use any font than none
+			path, err = findFontFile(DEFAULT_FONT_FAMILY, style)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	font_paths[name] = path
+	return path, nil
+}
+
+// Gets the file of the font fc-match picks for a pattern (e.g. "DejaVu Sans:style=Bold")
+func matchFont(pattern string) (path string, err error) {
+	output, err := exec.Command("fc-match", "--format=%{file}", pattern).Output()
+	if err != nil {
+		return "", fmt.Errorf("fc-match %s: %v", pattern, err)
+	}
+
+	path = strings.TrimSpace(string(output))
+	if path == "" {
+		return "", fmt.Errorf("no font found for %s", pattern)
+	}
+
+	return path, nil
+}
+
+// Finds a font file by its name (e.g. DejaVuSans-Bold.ttf for "DejaVu Sans" and "Bold")
+func findFontFile(family string, style string) (path string, err error) {
+	if font_files == nil {
+		font_files = scanFontFiles(getFontDirs())
+	}
+
+	// generic families are not part of the file names
+	if family == DEFAULT_FONT_FAMILY || family == "sans" {
+		family = "DejaVu Sans"
+	}
+
+	names := []string{family + style}
+	switch strings.ToLower(style) {
+	case "regular", "book", "normal", "roman":
+		names = append(names, family, family+"Regular", family+"Book")
+	}
+	if strings.Contains(strings.ToLower(style), "italic") {
+		names = append(names, family+strings.Replace(strings.ToLower(style), "italic", "oblique", -1))
+	}
+
+	for _, name := range names {
+		for _, file := range font_files {
+			if normalizeFontName(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))) == normalizeFontName(name) {
+				return file, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no font found for %s %s", family, style)
+}
+
+// Lowercases a font name and removes everything but letters and digits
+func normalizeFontName(name string) (normalized string) {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// The parts of fonts.conf needed to find the fonts
+type fontsConf struct {
+	Dirs []struct {
+		Prefix string `xml:"prefix,attr"`
+		Path   string `xml:",chardata"`
+	} `xml:"dir"`
+}
+
+// Gets the font directories of fonts.conf and the usual ones
+func getFontDirs() (dirs []string) {
+	var conf fontsConf
+	data, err := os.ReadFile(FONTS_CONF_PATH)
+	if err == nil {
+		err = xml.Unmarshal(data, &conf)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Println(err)
+	}
+
+	for _, dir := range conf.Dirs {
+		path := strings.TrimSpace(dir.Path)
+
+		switch dir.Prefix {
+		case "xdg":
+			data_home, err := getXDGDataHome()
+			if err != nil {
+				continue
+			}
+			path = filepath.Join(data_home, path)
+		case "relative":
+			path = filepath.Join(filepath.Dir(FONTS_CONF_PATH), path)
+		}
+
+		dirs = append(dirs, expandHomePath(path))
+	}
+
+	dirs = append(dirs, getXDGDataPaths("fonts")...)
+	for _, dir := range default_font_dirs {
+		dirs = append(dirs, expandHomePath(dir))
+	}
+
+	return dirs
+}
+
+// Finds all font files in the directories (and their subdirectories)
+func scanFontFiles(dirs []string) (files []string) {
+	found := make(map[string]bool)
+
+	for _, dir := range dirs {
+		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || found[path] {
+				return nil
+			}
+			if containsString(font_extensions, strings.ToLower(filepath.Ext(path))) {
+				found[path] = true
+				files = append(files, path)
+			}
+			return nil
+		})
+	}
+
+	return files
+}
+
+/*
+##############################################################
+# Section: Glyph fallback
+##############################################################
+*/
+
+// Checks if the font has a glyph
+func (font *LoadedFont) covers(r rune) bool {
+	return font.charset == nil || font.charset.Has(r)
+}
+
+// Gets the font used to draw a rune: the font itself or a fallback
+func (font *LoadedFont) fontFor(r rune) (used *LoadedFont) {
+	// spaces and control characters are never worth another font
+	if font.covers(r) || unicode.IsSpace(r) || unicode.IsControl(r) {
+		return font
+	}
+
+	fallback, ok := font.fallbacks[r]
+	if !ok {
+		fallback = font.findFallback(r)
+		font.fallbacks[r] = fallback
+
+		if fallback != nil && !containsFont(font.fallback_fonts, fallback) {
+			font.fallback_fonts = append(font.fallback_fonts, fallback)
+		}
+	}
+
+	if fallback == nil {
+		return font
+	}
+	return fallback
+}
+
+func containsFont(fonts []*LoadedFont, font *LoadedFont) bool {
+	for _, f := range fonts {
+		if f == font {
+			return true
+		}
+	}
+	return false
+}
+
+// Finds another font (at the same size) with a glyph
+func (font *LoadedFont) findFallback(r rune) (fallback *LoadedFont) {
+	// a fallback that was found before probably has more of the glyphs (e.g. an emoji font).
+	// The first one that has it is used, so the same text always gets the same fonts.
+	for _, other := range font.fallback_fonts {
+		if other.covers(r) {
+			return other
+		}
+	}
+
+	var candidates []string
+	if _, err := exec.LookPath("fc-match"); err == nil {
+		pattern := fmt.Sprintf("%s:style=%s:charset=%x", font.key.Family, font.key.Style, r)
+		if strings.Contains(font.key.Family, "/") {
+			pattern = fmt.Sprintf(":charset=%x", r)
+		}
+
+		path, err := matchFont(pattern)
+		if err != nil {
+			return nil
+		}
+		candidates = []string{path}
+	} else {
+		if font_files == nil {
+			font_files = scanFontFiles(getFontDirs())
+		}
+		candidates = font_files
+	}
+
+	for _, path := range candidates {
+		if path == font.path {
+			continue
+		}
+
+		// fc-match also returns fonts without the glyph if there is none
+		charset, err := getFontCharset(path)
+		if err != nil || !charset.Has(r) {
+			continue
+		}
+
+		fallback, err := loadFont(path, "", font.key.Size)
+		if err != nil {
+			continue
+		}
+		return fallback
+	}
+
+	return nil
+}
+
+// Splits a text into parts that are drawn with the same font
+func (font *LoadedFont) runs(text string) (runs []fontRun) {
+	start := 0
+	var current *LoadedFont
+
+	for i, r := range text {
+		used := font.fontFor(r)
+		if used != current && i > start {
+			runs = append(runs, fontRun{current, text[start:i]})
+			start = i
+		}
+		current = used
+	}
+
+	if start < len(text) {
+		runs = append(runs, fontRun{current, text[start:]})
+	}
+
+	return runs
+}
+
+/*
+##############################################################
+# Section: Drawing
+##############################################################
+*/
+
+// Gets the height of a line of text
+func (font *LoadedFont) Height() int {
+	return font.face.Height()
+}
+
+// Gets the size of the rendered text
+func (font *LoadedFont) SizeUTF8(text string) (w int, h int, err error) {
+	h = font.Height()
+
+	for _, run := range font.runs(text) {
+		run_w, run_h, err := run.font.face.SizeUTF8(run.text)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		w += run_w
+		if run_h > h {
+			h = run_h
+		}
+	}
+
+	return w, h, nil
+}
+
+// Gets the sdl_ttf font, only fonts opened for windows have one
+func (font *LoadedFont) ttfFont() (ttf_font *ttf.Font, err error) {
+	ttf_font, ok := font.face.(*ttf.Font)
+	if !ok {
+		return nil, fmt.Errorf("%s was not opened with sdl_ttf", font.path)
+	}
+	return ttf_font, nil
+}
+
+// Renders the text onto its background color
+func (font *LoadedFont) RenderUTF8Shaded(text string, fg sdl.Color, bg sdl.Color) (surf *sdl.Surface, err error) {
+	return font.render(text, fg, &bg)
+}
+
+// Renders the text onto a transparent surface
+func (font *LoadedFont) RenderUTF8Blended(text string, color sdl.Color) (surf *sdl.Surface, err error) {
+	return font.render(text, color, nil)
+}
+
+// Renders the runs of the text next to each other, on their common baseline
+func (font *LoadedFont) render(text string, fg sdl.Color, bg *sdl.Color) (surf *sdl.Surface, err error) {
+	runs := font.runs(text)
+
+	renderRun := func(run fontRun) (*sdl.Surface, error) {
+		ttf_font, err := run.font.ttfFont()
+		if err != nil {
+			return nil, err
+		}
+		if bg != nil {
+			return ttf_font.RenderUTF8Shaded(run.text, fg, *bg)
+		}
+		return ttf_font.RenderUTF8Blended(run.text, fg)
+	}
+
+	// nothing to put together
+	if len(runs) <= 1 {
+		return renderRun(fontRun{font, text})
+	}
+
+	w, h, err := font.SizeUTF8(text)
+	if err != nil {
+		return nil, err
+	}
+
+	// fonts with a higher ascent move the baseline down
+	ascent, descent := 0, 0
+	for _, run := range runs {
+		if run.font.face.Ascent() > ascent {
+			ascent = run.font.face.Ascent()
+		}
+		if run.font.Height()-run.font.face.Ascent() > descent {
+			descent = run.font.Height() - run.font.face.Ascent()
+		}
+	}
+	if ascent+descent > h {
+		h = ascent + descent
+	}
+
+	if bg != nil {
+		surf, err = sdl.CreateRGBSurface(0, int32(w), int32(h), 32, 0, 0, 0, 0)
+		if err == nil {
+			surf.FillRect(nil, ColorToUInt32(*bg))
+		}
+	} else {
+		surf, err = sdl.CreateRGBSurface(0, int32(w), int32(h), 32, 0x00ff0000, 0x0000ff00, 0x000000ff, 0xff000000)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	x := int32(0)
+	for _, run := range runs {
+		run_surface, err := renderRun(run)
+		if err != nil {
+			surf.Free()
+			return nil, err
+		}
+
+		// the runs do not overlap, so the transparent pixels are copied as they are
+		if bg == nil {
+			run_surface.SetBlendMode(sdl.BLENDMODE_NONE)
+		}
+
+		y := int32(ascent - run.font.face.Ascent())
+		run_surface.Blit(nil, surf, &sdl.Rect{X: x, Y: y, W: run_surface.W, H: run_surface.H})
+		x += run_surface.W
+		run_surface.Free()
+	}
+
+	return surf, nil
+}
+
+/*
+##############################################################
+# Section: Charsets
+##############################################################
+*/
+
+// Gets the glyphs of a font file (the first font of collections, like ttf)
+func getFontCharset(path string) (charset FontCharset, err error) {
+	if charset, ok := font_charsets[path]; ok {
+		return charset, nil
+	}
+
+	parsed, err := parseFontFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	charset = &sfntCharset{font: parsed}
+	font_charsets[path] = charset
+	return charset, nil
+}
+
+// Reads a font file, the first font of collections (.ttc)
+func parseFontFile(path string) (parsed *sfnt.Font, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// single fonts are read as collections of one font
+	collection, err := sfnt.ParseCollection(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return collection.Font(0)
+}
+
+// Checks if the cmap table of the font maps the rune to a glyph
+func (charset *sfntCharset) Has(r rune) bool {
+	index, err := charset.font.GlyphIndex(&charset.buffer, r)
+	return err == nil && index != 0
+}
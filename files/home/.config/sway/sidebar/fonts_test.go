@@ -0,0 +1,142 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// A charset with just the given runes
+type testCharset string
+
+func (charset testCharset) Has(r rune) bool {
+	for _, c := range charset {
+		if c == r {
+			return true
+		}
+	}
+	return false
+}
+
+// A face that measures nothing
+type testFace struct{}
+
+func (testFace) SizeUTF8(text string) (w int, h int, err error) { return 0, 0, nil }
+func (testFace) Height() int                                    { return 0 }
+func (testFace) Ascent() int                                    { return 0 }
+func (testFace) Close()                                         {}
+
+// Makes the font files the fallbacks are found in (without fc-match) the paths of the charsets,
+// which are opened without reading them
+func useTestFallbacks(t *testing.T, files []string, charsets map[string]FontCharset) {
+	t.Helper()
+
+	t.Setenv("PATH", t.TempDir())
+
+	old_files, old_charsets := font_files, font_charsets
+	font_files, font_charsets = files, charsets
+	openFontFace = func(path string, size int) (face FontFace, err error) {
+		return testFace{}, nil
+	}
+
+	t.Cleanup(func() {
+		closeFonts()
+		font_files, font_charsets = old_files, old_charsets
+		openFontFace = openTTFFace
+	})
+}
+
+func TestFontFallbackOrder(t *testing.T) {
+	useTestFallbacks(t, []string{"/fonts/a.ttf", "/fonts/b.ttf", "/fonts/c.ttf", "/fonts/d.ttf"}, map[string]FontCharset{
+		"/fonts/main.ttf": testCharset("abcx"),
+		"/fonts/a.ttf":    testCharset("aα"),
+		"/fonts/b.ttf":    testCharset("αβ★"),
+		"/fonts/c.ttf":    testCharset("★☃"),
+		"/fonts/d.ttf":    testCharset("☃"),
+	})
+
+	font, err := loadFont("/fonts/main.ttf", "", 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, run := range font.runs("ab α β★ ☃漢x") {
+		got = append(got, filepath.Base(run.font.path)+":"+run.text)
+	}
+
+	// the font itself first, then the fallbacks found before (in the order they were found),
+	// then the font files in their order. Spaces and runes no font has stay in the font.
+	want := []string{"main.ttf:ab ", "a.ttf:α", "main.ttf: ", "b.ttf:β★", "main.ttf: ", "c.ttf:☃", "main.ttf:漢x"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got runs %q, want %q", got, want)
+	}
+
+	var fallbacks []string
+	for _, fallback := range font.fallback_fonts {
+		fallbacks = append(fallbacks, filepath.Base(fallback.path))
+	}
+	if want := []string{"a.ttf", "b.ttf", "c.ttf"}; !reflect.DeepEqual(fallbacks, want) {
+		t.Errorf("got fallbacks %q, want %q", fallbacks, want)
+	}
+
+	// fallbacks have the size of the font, and are shared by all fonts of that size
+	if fallback := font.fontFor('☃'); fallback.key.Size != 12 || fallback != loaded_fonts[FontKey{"/fonts/c.ttf", "", 12}] {
+		t.Errorf("got fallback %+v", fallback.key)
+	}
+}
+
+func TestFontFallbackUnknownCharset(t *testing.T) {
+	useTestFallbacks(t, []string{"/fonts/a.ttf"}, map[string]FontCharset{
+		"/fonts/a.ttf": testCharset("漢"),
+	})
+
+	// fonts whose glyphs can not be read are assumed to have all of them
+	font, err := loadFont("/fonts/missing.ttf", "", 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if font.charset != nil || font.fontFor('漢') != font {
+		t.Errorf("a font without a charset uses a fallback")
+	}
+}
+
+func TestFontCharset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Go-Regular.ttf")
+	if err := os.WriteFile(path, goregular.TTF, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	old_charsets := font_charsets
+	font_charsets = make(map[string]FontCharset)
+	t.Cleanup(func() { font_charsets = old_charsets })
+
+	charset, err := getFontCharset(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range "Aaz0ßαЖ€" {
+		if !charset.Has(r) {
+			t.Errorf("the Go font has no %q", r)
+		}
+	}
+	for _, r := range "漢😀͸" {
+		if charset.Has(r) {
+			t.Errorf("the Go font has %q", r)
+		}
+	}
+
+	if _, err := getFontCharset(filepath.Join(filepath.Dir(path), "missing.ttf")); err == nil {
+		t.Error("got the charset of a missing file")
+	}
+	if err := os.WriteFile(path+".txt", []byte("not a font"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := getFontCharset(path + ".txt"); err == nil {
+		t.Error("got the charset of a text file")
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+/*
+##############################################################
+# Section: Imports
+##############################################################
+*/
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+/*
+######################################################################################################
+######################################################################################################
+## Chapter: Launch History																			##
+######################################################################################################
+######################################################################################################
+*/
+
+/*
+##############################################################
+# Section: Constants & Fields
+##############################################################
+*/
+
+// The number of launch times remembered per entry
+const HISTORY_LENGTH = 10
+
+// After this time, a launch counts half as much for the frecency (see FrecencyHalfLife in the config file)
+var FRECENCY_HALF_LIFE = 7 * 24 * time.Hour
+
+// How much launches count that are older than the remembered ones
+// (or from before launch times were recorded)
+const FRECENCY_OLD_WEIGHT = 0.01
+
+/*
+##############################################################
+# Section: History
+##############################################################
+*/
+
+// The launches of a single entry.
+// In the data file, a row is: key, count, launch times (unix seconds, newest first)
+type LaunchHistory struct {
+	Count    int
+	Launches []time.Time
+}
+
+// Parses the values of a data file row
+func parseLaunchHistory(values []string) (history LaunchHistory) {
+	if len(values) == 0 {
+		return history
+	}
+
+	history.Count, _ = strconv.Atoi(values[0])
+
+	for _, value := range values[1:] {
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		history.Launches = append(history.Launches, time.Unix(seconds, 0))
+	}
+
+	return history
+}
+
+// Formats the history as the values of a data file row
+func (history LaunchHistory) values() (values []string) {
+	values = []string{strconv.Itoa(history.Count)}
+	for _, launch := range history.Launches {
+		values = append(values, strconv.FormatInt(launch.Unix(), 10))
+	}
+	return values
+}
+
+// Adds a launch, forgetting the oldest one if there are too many
+func (history *LaunchHistory) add(launch time.Time) {
+	history.Count++
+	history.Launches = append([]time.Time{launch}, history.Launches...)
+	if len(history.Launches) > HISTORY_LENGTH {
+		history.Launches = history.Launches[:HISTORY_LENGTH]
+	}
+}
+
+// Calculates how frequently and recently the entry was launched.
+// Every remembered launch counts 1 when it just happened, decaying by FRECENCY_HALF_LIFE.
+// Forgotten launches count FRECENCY_OLD_WEIGHT at most.
+func (history LaunchHistory) Frecency(now time.Time) (score float64) {
+	weight := func(launch time.Time) float64 {
+		age := now.Sub(launch)
+		if age < 0 {
+			age = 0
+		}
+		return math.Pow(0.5, float64(age)/float64(FRECENCY_HALF_LIFE))
+	}
+
+	for _, launch := range history.Launches {
+		score += weight(launch)
+	}
+
+	// the forgotten launches happened before the oldest remembered one
+	forgotten := history.Count - len(history.Launches)
+	if forgotten > 0 {
+		old_weight := FRECENCY_OLD_WEIGHT
+		if len(history.Launches) > 0 {
+			old_weight = math.Min(old_weight, weight(history.Launches[len(history.Launches)-1]))
+		}
+		score += float64(forgotten) * old_weight
+	}
+
+	return score
+}
+
+/*
+##############################################################
+# Section: Data file
+##############################################################
+*/
+
+// Reads the launch histories of all entries from the data file
+func loadLaunchHistories() (histories map[string]LaunchHistory, err error) {
+	histories = make(map[string]LaunchHistory)
+
+	path, err := getDataFilePath()
+	if err != nil {
+		return histories, err
+	}
+
+	data, err := readDataFile(path)
+	if err != nil {
+		return histories, err
+	}
+
+	for key, values := range data {
+		histories[key] = parseLaunchHistory(values)
+	}
+
+	return histories, nil
+}
+
+// Gets the frecency of every entry in the data file.
+// Entries that were never launched are missing (their score is 0).
+func getFrecencyScores() (scores map[string]float64) {
+	scores = make(map[string]float64)
+
+	histories, err := loadLaunchHistories()
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	now := time.Now()
+	for key, history := range histories {
+		scores[key] = history.Frecency(now)
+	}
+
+	return scores
+}
+
+// Records that an entry (a desktop file path) was launched now.
+// Entries of desktop files that no longer exist are removed on the way.
+func recordLaunch(key string) (err error) {
+	path, err := getDataFilePath()
+	if err != nil {
+		return err
+	}
+
+	return updateDataFile(path, func(data map[string][]string) error {
+		for other := range data {
+			if _, err := os.Stat(other); os.IsNotExist(err) {
+				delete(data, other)
+			}
+		}
+
+		history := parseLaunchHistory(data[key])
+		history.add(time.Now())
+		data[key] = history.values()
+
+		return nil
+	})
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Sets FRECENCY_HALF_LIFE through the config key, like the config file does
+func setTestHalfLife(t *testing.T, days string) {
+	t.Helper()
+
+	old_half_life := FRECENCY_HALF_LIFE
+	t.Cleanup(func() { FRECENCY_HALF_LIFE = old_half_life })
+
+	if err := config_keys["FrecencyHalfLife"](days); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFrecency(t *testing.T) {
+	setTestHalfLife(t, "7")
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name    string
+		history LaunchHistory
+		want    float64
+	}{
+		{"never", LaunchHistory{}, 0},
+		{"just now", LaunchHistory{1, []time.Time{now}}, 1},
+		{"one half life ago", LaunchHistory{1, []time.Time{now.Add(-7 * day)}}, 0.5},
+		{"two half lives ago", LaunchHistory{1, []time.Time{now.Add(-14 * day)}}, 0.25},
+		{"several", LaunchHistory{3, []time.Time{now, now.Add(-7 * day), now.Add(-14 * day)}}, 1.75},
+
+		// launches in the future (a clock that was changed) count as now
+		{"in the future", LaunchHistory{1, []time.Time{now.Add(day)}}, 1},
+
+		// launches from before the times were recorded
+		{"only forgotten", LaunchHistory{4, nil}, 4 * FRECENCY_OLD_WEIGHT},
+		{"forgotten after recent", LaunchHistory{3, []time.Time{now}}, 1 + 2*FRECENCY_OLD_WEIGHT},
+
+		// forgotten launches never count more than the oldest remembered one
+		{"forgotten after old", LaunchHistory{2, []time.Time{now.Add(-70 * day)}}, 2 * math.Pow(0.5, 10)},
+	}
+
+	for _, test := range tests {
+		if got := test.history.Frecency(now); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s: frecency is %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestFrecencyHalfLife(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	history := LaunchHistory{1, []time.Time{now.Add(-24 * time.Hour)}}
+
+	setTestHalfLife(t, "1")
+	if got := history.Frecency(now); math.Abs(got-0.5) > 1e-9 {
+		t.Errorf("a launch one half life (1 day) ago counts %v", got)
+	}
+
+	setTestHalfLife(t, "0.5")
+	if got := history.Frecency(now); math.Abs(got-0.25) > 1e-9 {
+		t.Errorf("a launch two half lives (12 hours) ago counts %v", got)
+	}
+
+	// a shorter half life prefers recent launches more
+	often := LaunchHistory{4, []time.Time{now.Add(-72 * time.Hour), now.Add(-73 * time.Hour), now.Add(-74 * time.Hour), now.Add(-75 * time.Hour)}}
+	recent := LaunchHistory{1, []time.Time{now.Add(-time.Hour)}}
+
+	setTestHalfLife(t, "30")
+	if often.Frecency(now) <= recent.Frecency(now) {
+		t.Error("with a long half life, 4 launches 3 days ago count less than 1 an hour ago")
+	}
+
+	setTestHalfLife(t, "1")
+	if often.Frecency(now) >= recent.Frecency(now) {
+		t.Error("with a short half life, 4 launches 3 days ago count more than 1 an hour ago")
+	}
+}
+
+func TestFrecencyHalfLifeConfig(t *testing.T) {
+	setTestHalfLife(t, "2.5")
+	if FRECENCY_HALF_LIFE != 60*time.Hour {
+		t.Errorf("2.5 days are %v", FRECENCY_HALF_LIFE)
+	}
+
+	for _, value := range []string{"0", "-1", "week", "Inf", "NaN", "1e10"} {
+		if err := config_keys["FrecencyHalfLife"](value); err == nil {
+			t.Errorf("FrecencyHalfLife=%s was accepted", value)
+		}
+	}
+	if FRECENCY_HALF_LIFE != 60*time.Hour {
+		t.Errorf("invalid values changed the half life to %v", FRECENCY_HALF_LIFE)
+	}
+}
+
+func TestLaunchHistoryValues(t *testing.T) {
+	history := parseLaunchHistory([]string{"12", "1709294400", "junk", "1709208000"})
+
+	want := LaunchHistory{12, []time.Time{time.Unix(1709294400, 0), time.Unix(1709208000, 0)}}
+	if !reflect.DeepEqual(history, want) {
+		t.Errorf("parsed %+v, want %+v", history, want)
+	}
+
+	if values := history.values(); !reflect.DeepEqual(values, []string{"12", "1709294400", "1709208000"}) {
+		t.Errorf("formatted as %q", values)
+	}
+
+	// rows of files from before version 2 only have the count
+	if history := parseLaunchHistory([]string{"3"}); history.Count != 3 || history.Launches != nil {
+		t.Errorf("parsed %+v from a count", history)
+	}
+}
+
+func TestLaunchHistoryAdd(t *testing.T) {
+	start := time.Unix(1709294400, 0)
+
+	var history LaunchHistory
+	for i := 0; i < HISTORY_LENGTH+3; i++ {
+		history.add(start.Add(time.Duration(i) * time.Hour))
+	}
+
+	if history.Count != HISTORY_LENGTH+3 || len(history.Launches) != HISTORY_LENGTH {
+		t.Fatalf("%d launches remembered of %d", len(history.Launches), history.Count)
+	}
+
+	// newest first, the oldest ones are forgotten
+	if !history.Launches[0].Equal(start.Add(time.Duration(HISTORY_LENGTH+2) * time.Hour)) {
+		t.Errorf("the newest launch is %v", history.Launches[0])
+	}
+	if !history.Launches[HISTORY_LENGTH-1].Equal(start.Add(3 * time.Hour)) {
+		t.Errorf("the oldest launch is %v", history.Launches[HISTORY_LENGTH-1])
+	}
+}
@@ -0,0 +1,590 @@
+package main
+
+/*
+##############################################################
+# Section: Imports
+##############################################################
+*/
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+)
+
+/*
+######################################################################################################
+######################################################################################################
+## Chapter: Icon Themes																				##
+######################################################################################################
+######################################################################################################
+*/
+
+// Implements https://specifications.freedesktop.org/icon-theme-spec/latest/
+
+/*
+##############################################################
+# Section: Constants & Fields
+##############################################################
+*/
+
+// Every theme falls back to this one
+const FALLBACK_ICON_THEME = "hicolor"
+
+// Extensions of icon files in the order they are preferred.
+// The spec also allows .xpm, but there is no decoder for it, so those files are skipped.
+var icon_extensions = []string{".png", ".svg"}
+
+// Extensions of icon files that cannot be loaded
+var unsupported_icon_extensions = []string{".xpm"}
+
+// The resolver used by the windows, see getIconResolver()
+var icon_resolver *IconResolver
+
+/*
+##############################################################
+# Section: Types
+##############################################################
+*/
+
+type IconTheme struct {
+	Name     string
+	Inherits []string
+
+	// the directories of the theme in the base directories
+	paths []string
+
+	Directories []IconDirectory
+}
+
+// A directory of an icon theme, containing icons of one size
+type IconDirectory struct {
+	Path      string
+	Size      int
+	Scale     int
+	MinSize   int
+	MaxSize   int
+	Threshold int
+	Type      string
+}
+
+type IconResolver struct {
+	theme     string
+	base_dirs []string
+	themes    map[string]*IconTheme
+
+	// results of previous lookups (also from previous runs)
+	cache map[string]string
+	stamp string
+	dirty bool
+}
+
+// The format of the cache file
+type iconCacheFile struct {
+	Stamp string            `json:"stamp"`
+	Icons map[string]string `json:"icons"`
+}
+
+/*
+##############################################################
+# Section: Resolver
+##############################################################
+*/
+
+// Gets the shared icon resolver for the users icon theme
+func getIconResolver() (resolver *IconResolver) {
+	if icon_resolver == nil {
+		icon_resolver = NewIconResolver(getIconThemeName())
+	}
+	return icon_resolver
+}
+
+// Creates a resolver for the given theme and loads the cache of previous runs
+func NewIconResolver(theme string) (resolver *IconResolver) {
+	resolver = &IconResolver{
+		theme:     theme,
+		base_dirs: getIconBaseDirs(),
+		themes:    make(map[string]*IconTheme),
+		cache:     make(map[string]string),
+	}
+
+	resolver.stamp = resolver.computeStamp()
+	resolver.loadCache()
+
+	return resolver
+}
+
+// Finds the file of an icon. name may also be an absolute path.
+// Returns "" if there is no such icon.
+func (resolver *IconResolver) Lookup(name string, size int, scale int) (path string) {
+	if name == "" {
+		return ""
+	}
+
+	// those cannot be loaded, but the icon may exist in another format in the themes
+	for _, extension := range unsupported_icon_extensions {
+		if strings.HasSuffix(name, extension) {
+			name = strings.TrimSuffix(filepath.Base(name), extension)
+		}
+	}
+
+	// absolute paths are used as they are
+	if filepath.IsAbs(name) {
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+		return ""
+	}
+
+	// some entries add an extension, even though they should not
+	for _, extension := range icon_extensions {
+		name = strings.TrimSuffix(name, extension)
+	}
+
+	key := fmt.Sprintf("%s/%d@%d", name, size, scale)
+	if path, ok := resolver.cache[key]; ok {
+		// make sure the icon was not removed in the meantime
+		if path == "" {
+			return path
+		}
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+
+	path = resolver.findIconHelper(name, size, scale, resolver.theme, make(map[string]bool))
+	if path == "" && resolver.theme != FALLBACK_ICON_THEME {
+		path = resolver.findIconHelper(name, size, scale, FALLBACK_ICON_THEME, make(map[string]bool))
+	}
+	if path == "" {
+		path = resolver.lookupFallbackIcon(name)
+	}
+
+	resolver.cache[key] = path
+	resolver.dirty = true
+
+	return path
+}
+
+// Looks through a theme and the themes it inherits from
+func (resolver *IconResolver) findIconHelper(name string, size int, scale int, themename string, visited map[string]bool) (path string) {
+	if visited[themename] {
+		return ""
+	}
+	visited[themename] = true
+
+	theme := resolver.loadTheme(themename)
+	if theme == nil {
+		return ""
+	}
+
+	path = resolver.lookupIcon(name, size, scale, theme)
+	if path != "" {
+		return path
+	}
+
+	for _, parent := range theme.Inherits {
+		path = resolver.findIconHelper(name, size, scale, parent, visited)
+		if path != "" {
+			return path
+		}
+	}
+
+	return ""
+}
+
+// Looks for the icon in the directories of a single theme.
+// Exact size matches win, otherwise the closest size is used.
+func (resolver *IconResolver) lookupIcon(name string, size int, scale int, theme *IconTheme) (path string) {
+	for _, dir := range theme.Directories {
+		if !dir.matchesSize(size, scale) {
+			continue
+		}
+		if path = theme.findFile(dir, name); path != "" {
+			return path
+		}
+	}
+
+	minimal_distance := math.MaxInt32
+	closest := ""
+	for _, dir := range theme.Directories {
+		distance := dir.sizeDistance(size, scale)
+		if distance >= minimal_distance {
+			continue
+		}
+		if path = theme.findFile(dir, name); path != "" {
+			closest = path
+			minimal_distance = distance
+		}
+	}
+
+	return closest
+}
+
+// Looks for icons outside of themes (e.g. /usr/share/pixmaps)
+func (resolver *IconResolver) lookupFallbackIcon(name string) (path string) {
+	for _, dir := range resolver.base_dirs {
+		for _, extension := range icon_extensions {
+			path = filepath.Join(dir, name+extension)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// Finds the icon file in one directory of the theme
+func (theme *IconTheme) findFile(dir IconDirectory, name string) (path string) {
+	for _, themepath := range theme.paths {
+		for _, extension := range icon_extensions {
+			path = filepath.Join(themepath, dir.Path, name+extension)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+func (dir IconDirectory) matchesSize(size int, scale int) bool {
+	if dir.Scale != scale {
+		return false
+	}
+
+	switch dir.Type {
+	case "Fixed":
+		return dir.Size == size
+	case "Scalable":
+		return dir.MinSize <= size && size <= dir.MaxSize
+	default: // Threshold
+		return dir.Size-dir.Threshold <= size && size <= dir.Size+dir.Threshold
+	}
+}
+
+func (dir IconDirectory) sizeDistance(size int, scale int) (distance int) {
+	wanted := size * scale
+
+	switch dir.Type {
+	case "Fixed":
+		distance = dir.Size*dir.Scale - wanted
+		if distance < 0 {
+			distance = -distance
+		}
+		return distance
+	case "Scalable":
+		if wanted < dir.MinSize*dir.Scale {
+			return dir.MinSize*dir.Scale - wanted
+		}
+		if wanted > dir.MaxSize*dir.Scale {
+			return wanted - dir.MaxSize*dir.Scale
+		}
+		return 0
+	default: // Threshold
+		if wanted < (dir.Size-dir.Threshold)*dir.Scale {
+			return (dir.Size-dir.Threshold)*dir.Scale - wanted
+		}
+		if wanted > (dir.Size+dir.Threshold)*dir.Scale {
+			return wanted - (dir.Size+dir.Threshold)*dir.Scale
+		}
+		return 0
+	}
+}
+
+/*
+##############################################################
+# Section: Themes
+##############################################################
+*/
+
+// Loads (and remembers) the index.theme of a theme, nil if it is not installed
+func (resolver *IconResolver) loadTheme(name string) (theme *IconTheme) {
+	if theme, ok := resolver.themes[name]; ok {
+		return theme
+	}
+
+	// the first index.theme found is the one that counts,
+	// but icons may be in the theme directory of any base directory
+	var index *DesktopEntry
+	var paths []string
+	for _, dir := range resolver.base_dirs {
+		themepath := filepath.Join(dir, name)
+		if info, err := os.Stat(themepath); err != nil || !info.IsDir() {
+			continue
+		}
+		paths = append(paths, themepath)
+
+		if index != nil {
+			continue
+		}
+
+		file, err := os.Open(filepath.Join(themepath, "index.theme"))
+		if err != nil {
+			continue
+		}
+		index, err = parseKeyFile(file)
+		file.Close()
+		if err != nil {
+			if _, ok := err.(DesktopEntryErrors); !ok {
+				index = nil
+			}
+		}
+	}
+
+	if index == nil || index.Group("Icon Theme") == nil {
+		resolver.themes[name] = nil
+		return nil
+	}
+
+	main := index.Group("Icon Theme")
+	theme = &IconTheme{Name: name, paths: paths, Inherits: main.List("Inherits")}
+
+	// hicolor is always the last resort, so nothing but hicolor has to inherit it explicitly
+	if len(theme.Inherits) == 0 && name != FALLBACK_ICON_THEME {
+		theme.Inherits = []string{FALLBACK_ICON_THEME}
+	}
+
+	subdirs := append(main.List("Directories"), main.List("ScaledDirectories")...)
+	for _, subdir := range subdirs {
+		group := index.Group(subdir)
+		if group == nil {
+			continue
+		}
+
+		number := func(key string, def int) int {
+			raw, ok := group.Raw(key)
+			if !ok {
+				return def
+			}
+			value, err := strconv.Atoi(raw)
+			if err != nil {
+				return def
+			}
+			return value
+		}
+
+		dir := IconDirectory{Path: subdir, Type: group.String("Type")}
+		dir.Size = number("Size", 0)
+		dir.Scale = number("Scale", 1)
+		dir.MinSize = number("MinSize", dir.Size)
+		dir.MaxSize = number("MaxSize", dir.Size)
+		dir.Threshold = number("Threshold", 2)
+		if dir.Type == "" {
+			dir.Type = "Threshold"
+		}
+
+		theme.Directories = append(theme.Directories, dir)
+	}
+
+	resolver.themes[name] = theme
+	return theme
+}
+
+// Gets the directories icons and themes are searched in, in the order of the spec
+func getIconBaseDirs() (dirs []string) {
+	home, err := getHomePath()
+	if err == nil {
+		dirs = append(dirs, filepath.Join(home, ".icons"))
+	}
+
+	dirs = append(dirs, getXDGDataPaths("icons")...)
+
+	return append(dirs, "/usr/share/pixmaps")
+}
+
+// Gets the icon theme the user configured for gtk, hicolor if there is none
+func getIconThemeName() (name string) {
+	config_home, err := getXDGConfigHome()
+	if err != nil {
+		return FALLBACK_ICON_THEME
+	}
+
+	file, err := os.Open(filepath.Join(config_home, "gtk-3.0", "settings.ini"))
+	if err != nil {
+		return FALLBACK_ICON_THEME
+	}
+	defer file.Close()
+
+	settings, _ := parseKeyFile(file)
+	if group := settings.Group("Settings"); group != nil {
+		if name = group.String("gtk-icon-theme-name"); name != "" {
+			return name
+		}
+	}
+
+	return FALLBACK_ICON_THEME
+}
+
+/*
+##############################################################
+# Section: Icon images
+##############################################################
+*/
+
+// Loads an icon file. SVG icons are rendered at exactly size x size pixels,
+// other formats are returned at the size they have (use scaleImage for those).
+func decodeIconImage(path string, size int) (img image.Image, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".svg") {
+		img, err = rasterizeSVG(file, size)
+	} else {
+		img, _, err = image.Decode(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return img, nil
+}
+
+// Renders an svg into a size x size image, keeping its aspect ratio
+func rasterizeSVG(reader io.Reader, size int) (img *image.RGBA, err error) {
+	// unsupported elements are skipped, most icons still look fine without them
+	icon, err := oksvg.ReadIconStream(reader, oksvg.IgnoreErrorMode)
+	if err != nil {
+		return nil, err
+	}
+
+	// fit the view box into the square and center it
+	w, h := float64(size), float64(size)
+	if icon.ViewBox.W > 0 && icon.ViewBox.H > 0 {
+		if icon.ViewBox.W > icon.ViewBox.H {
+			h = w * icon.ViewBox.H / icon.ViewBox.W
+		} else {
+			w = h * icon.ViewBox.W / icon.ViewBox.H
+		}
+	}
+	icon.SetTarget((float64(size)-w)/2, (float64(size)-h)/2, w, h)
+
+	img = image.NewRGBA(image.Rect(0, 0, size, size))
+	scanner := rasterx.NewScannerGV(size, size, img, img.Bounds())
+	icon.Draw(rasterx.NewDasher(size, size, scanner), 1.0)
+
+	return img, nil
+}
+
+/*
+##############################################################
+# Section: Cache
+##############################################################
+*/
+
+func getIconCachePath() (path string, err error) {
+	cache_dir, err := getCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(cache_dir, "icons.json"), nil
+}
+
+// Computes a value that changes whenever icons are (un)installed.
+// Installing icons updates the modification time of the theme directories
+// (or at least their icon-theme.cache), so these are enough to look at.
+func (resolver *IconResolver) computeStamp() (stamp string) {
+	hash := md5.New()
+	// results found with other extensions (e.g. .xpm files of older versions) are not used
+	fmt.Fprintln(hash, resolver.theme, icon_extensions)
+
+	for _, dir := range resolver.base_dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			for _, path := range []string{filepath.Join(dir, entry.Name()), filepath.Join(dir, entry.Name(), "icon-theme.cache")} {
+				if info, err := os.Stat(path); err == nil {
+					fmt.Fprintln(hash, path, info.ModTime().UnixNano())
+				}
+			}
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Loads the results of previous runs, unless icons changed since then
+func (resolver *IconResolver) loadCache() {
+	path, err := getIconCachePath()
+	if err != nil {
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	var cache iconCacheFile
+	if json.Unmarshal(data, &cache) != nil || cache.Stamp != resolver.stamp || cache.Icons == nil {
+		return
+	}
+
+	resolver.cache = cache.Icons
+}
+
+// Saves the lookups of the windows (if they looked up any icons) for the next run
+func saveIconCache() {
+	if icon_resolver == nil {
+		return
+	}
+
+	err := icon_resolver.SaveCache()
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+// Saves the results of the lookups for the next run
+func (resolver *IconResolver) SaveCache() (err error) {
+	if !resolver.dirty {
+		return nil
+	}
+
+	path, err := getIconCachePath()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(iconCacheFile{Stamp: resolver.stamp, Icons: resolver.cache})
+	if err != nil {
+		return err
+	}
+
+	// write the new cache next to the old one and replace it afterwards
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return err
+	}
+
+	resolver.dirty = false
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Writes files (path relative to dir: content), creating the directories they are in
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestIconLookupSkipsXPM(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	icons := t.TempDir()
+	pixmaps := t.TempDir()
+	writeTestFiles(t, icons, map[string]string{
+		"hicolor/index.theme":          "[Icon Theme]\nName=Hicolor\nDirectories=48x48/apps\n\n[48x48/apps]\nSize=48\nType=Fixed\n",
+		"hicolor/48x48/apps/old.xpm":   "",
+		"hicolor/48x48/apps/mixed.xpm": "",
+		"hicolor/48x48/apps/mixed.svg": "",
+	})
+	writeTestFiles(t, pixmaps, map[string]string{
+		"old.png":  "",
+		"only.xpm": "",
+	})
+
+	resolver := &IconResolver{
+		theme:     FALLBACK_ICON_THEME,
+		base_dirs: []string{icons, pixmaps},
+		themes:    make(map[string]*IconTheme),
+		cache:     make(map[string]string),
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		// the icon in another format is used instead
+		{"mixed", filepath.Join(icons, "hicolor/48x48/apps/mixed.svg")},
+		{"old", filepath.Join(pixmaps, "old.png")},
+		{"old.xpm", filepath.Join(pixmaps, "old.png")},
+		{filepath.Join(pixmaps, "old.xpm"), filepath.Join(pixmaps, "old.png")},
+
+		// without one, there is no icon
+		{"only", ""},
+		{filepath.Join(pixmaps, "only.xpm"), ""},
+
+		// other absolute paths are used as they are
+		{filepath.Join(pixmaps, "old.png"), filepath.Join(pixmaps, "old.png")},
+	}
+
+	for _, test := range tests {
+		if got := resolver.Lookup(test.name, 48, 1); got != test.want {
+			t.Errorf("Lookup(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
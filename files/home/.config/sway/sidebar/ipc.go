@@ -0,0 +1,418 @@
+package main
+
+/*
+##############################################################
+# Section: Imports
+##############################################################
+*/
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"unsafe"
+)
+
+/*
+######################################################################################################
+######################################################################################################
+## Chapter: Sway IPC																				##
+######################################################################################################
+######################################################################################################
+*/
+
+/*
+##############################################################
+# Section: Constants & Fields
+##############################################################
+*/
+
+// The magic string every i3-ipc message starts with
+const IPC_MAGIC = "i3-ipc"
+
+// magic + payload length + payload type
+const IPC_HEADER_SIZE = len(IPC_MAGIC) + 4 + 4
+
+// message types
+const (
+	IPC_RUN_COMMAND    uint32 = 0
+	IPC_GET_WORKSPACES uint32 = 1
+	IPC_SUBSCRIBE      uint32 = 2
+	IPC_GET_OUTPUTS    uint32 = 3
+	IPC_GET_TREE       uint32 = 4
+	IPC_GET_VERSION    uint32 = 7
+	IPC_GET_CONFIG     uint32 = 9
+)
+
+// events have the highest bit of their type set
+const IPC_EVENT_MASK uint32 = 0x80000000
+
+// event types
+const (
+	IPC_EVENT_WORKSPACE uint32 = 0x80000000
+	IPC_EVENT_OUTPUT    uint32 = 0x80000001
+	IPC_EVENT_MODE      uint32 = 0x80000002
+	IPC_EVENT_WINDOW    uint32 = 0x80000003
+	IPC_EVENT_BINDING   uint32 = 0x80000005
+	IPC_EVENT_SHUTDOWN  uint32 = 0x80000006
+	IPC_EVENT_TICK      uint32 = 0x80000007
+)
+
+// The connection to sway used by the window handlers.
+// nil if sway could not be reached.
+var sway_ipc *IPCConnection
+
+// i3-ipc uses the byte order of the machine it runs on
+var native_endian binary.ByteOrder
+
+func init() {
+	probe := uint16(1)
+	if (*[2]byte)(unsafe.Pointer(&probe))[0] == 1 {
+		native_endian = binary.LittleEndian
+	} else {
+		native_endian = binary.BigEndian
+	}
+}
+
+/*
+##############################################################
+# Section: Reply types
+##############################################################
+*/
+
+type IPCRect struct {
+	X      int32 `json:"x"`
+	Y      int32 `json:"y"`
+	Width  int32 `json:"width"`
+	Height int32 `json:"height"`
+}
+
+type CommandResult struct {
+	Success    bool   `json:"success"`
+	ParseError bool   `json:"parse_error"`
+	Error      string `json:"error"`
+}
+
+type Workspace struct {
+	Num     int     `json:"num"`
+	Name    string  `json:"name"`
+	Visible bool    `json:"visible"`
+	Focused bool    `json:"focused"`
+	Urgent  bool    `json:"urgent"`
+	Rect    IPCRect `json:"rect"`
+	Output  string  `json:"output"`
+}
+
+type OutputMode struct {
+	Width   int32 `json:"width"`
+	Height  int32 `json:"height"`
+	Refresh int32 `json:"refresh"`
+}
+
+type Output struct {
+	Name             string       `json:"name"`
+	Make             string       `json:"make"`
+	Model            string       `json:"model"`
+	Serial           string       `json:"serial"`
+	Active           bool         `json:"active"`
+	Primary          bool         `json:"primary"`
+	Scale            float64      `json:"scale"`
+	Transform        string       `json:"transform"`
+	CurrentWorkspace string       `json:"current_workspace"`
+	Modes            []OutputMode `json:"modes"`
+	CurrentMode      OutputMode   `json:"current_mode"`
+	Rect             IPCRect      `json:"rect"`
+}
+
+// A node of the layout tree (root, outputs, workspaces, containers and views)
+type Node struct {
+	ID                 int64   `json:"id"`
+	Name               string  `json:"name"`
+	Type               string  `json:"type"`
+	Border             string  `json:"border"`
+	CurrentBorderWidth int32   `json:"current_border_width"`
+	Layout             string  `json:"layout"`
+	Orientation        string  `json:"orientation"`
+	Percent            float64 `json:"percent"`
+	Rect               IPCRect `json:"rect"`
+	WindowRect         IPCRect `json:"window_rect"`
+	DecoRect           IPCRect `json:"deco_rect"`
+	Geometry           IPCRect `json:"geometry"`
+	Urgent             bool    `json:"urgent"`
+	Focused            bool    `json:"focused"`
+	Visible            bool    `json:"visible"`
+	Focus              []int64 `json:"focus"`
+	Nodes              []*Node `json:"nodes"`
+	FloatingNodes      []*Node `json:"floating_nodes"`
+	Output             string  `json:"output"`
+	Num                int     `json:"num"`
+	AppID              string  `json:"app_id"`
+	PID                int     `json:"pid"`
+	Window             int64   `json:"window"`
+}
+
+// Returns the first node (depth first, including itself) match returns true for
+func (node *Node) Find(match func(*Node) bool) (found *Node) {
+	if match(node) {
+		return node
+	}
+
+	for _, children := range [][]*Node{node.Nodes, node.FloatingNodes} {
+		for _, child := range children {
+			if found = child.Find(match); found != nil {
+				return found
+			}
+		}
+	}
+
+	return nil
+}
+
+// Returns the focused node of the tree or nil
+func (node *Node) FindFocused() (focused *Node) {
+	return node.Find(func(n *Node) bool { return n.Focused })
+}
+
+type Version struct {
+	Major                int    `json:"major"`
+	Minor                int    `json:"minor"`
+	Patch                int    `json:"patch"`
+	HumanReadable        string `json:"human_readable"`
+	LoadedConfigFileName string `json:"loaded_config_file_name"`
+}
+
+/*
+##############################################################
+# Section: Event types
+##############################################################
+*/
+
+// A raw event received on a subscribed connection
+type IPCEvent struct {
+	Type    uint32
+	Payload []byte
+}
+
+type WorkspaceEvent struct {
+	Change  string `json:"change"`
+	Current *Node  `json:"current"`
+	Old     *Node  `json:"old"`
+}
+
+type WindowEvent struct {
+	Change    string `json:"change"`
+	Container *Node  `json:"container"`
+}
+
+// Decodes the payload of a workspace event
+func (event IPCEvent) Workspace() (wevent WorkspaceEvent, err error) {
+	if event.Type != IPC_EVENT_WORKSPACE {
+		return wevent, fmt.Errorf("event type %#x is not a workspace event", event.Type)
+	}
+
+	err = json.Unmarshal(event.Payload, &wevent)
+	return wevent, err
+}
+
+// Decodes the payload of a window event
+func (event IPCEvent) Window() (wevent WindowEvent, err error) {
+	if event.Type != IPC_EVENT_WINDOW {
+		return wevent, fmt.Errorf("event type %#x is not a window event", event.Type)
+	}
+
+	err = json.Unmarshal(event.Payload, &wevent)
+	return wevent, err
+}
+
+/*
+##############################################################
+# Section: Connection
+##############################################################
+*/
+
+type IPCConnection struct {
+	conn  net.Conn
+	mutex sync.Mutex
+}
+
+// Connects to the socket found in $SWAYSOCK (or $I3SOCK)
+func ConnectIPC() (ipc *IPCConnection, err error) {
+	path := os.Getenv("SWAYSOCK")
+	if path == "" {
+		path = os.Getenv("I3SOCK")
+	}
+	if path == "" {
+		return nil, errors.New("Enviroment variable $SWAYSOCK not found")
+	}
+
+	return ConnectIPCPath(path)
+}
+
+// Connects to the unix socket at path
+func ConnectIPCPath(path string) (ipc *IPCConnection, err error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IPCConnection{conn: conn}, nil
+}
+
+func (ipc *IPCConnection) Close() (err error) {
+	return ipc.conn.Close()
+}
+
+// Writes a single message onto the socket
+func (ipc *IPCConnection) sendMessage(msgtype uint32, payload []byte) (err error) {
+	message := make([]byte, IPC_HEADER_SIZE, IPC_HEADER_SIZE+len(payload))
+	copy(message, IPC_MAGIC)
+	native_endian.PutUint32(message[len(IPC_MAGIC):], uint32(len(payload)))
+	native_endian.PutUint32(message[len(IPC_MAGIC)+4:], msgtype)
+	message = append(message, payload...)
+
+	_, err = ipc.conn.Write(message)
+	return err
+}
+
+// Reads a single message from the socket
+func (ipc *IPCConnection) readMessage() (msgtype uint32, payload []byte, err error) {
+	header := make([]byte, IPC_HEADER_SIZE)
+	if _, err = io.ReadFull(ipc.conn, header); err != nil {
+		return 0, nil, err
+	}
+
+	if string(header[:len(IPC_MAGIC)]) != IPC_MAGIC {
+		return 0, nil, fmt.Errorf("invalid ipc magic %q", header[:len(IPC_MAGIC)])
+	}
+
+	length := native_endian.Uint32(header[len(IPC_MAGIC):])
+	msgtype = native_endian.Uint32(header[len(IPC_MAGIC)+4:])
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(ipc.conn, payload); err != nil {
+		return 0, nil, err
+	}
+
+	return msgtype, payload, nil
+}
+
+// Sends a message and decodes the reply into reply
+func (ipc *IPCConnection) request(msgtype uint32, payload []byte, reply interface{}) (err error) {
+	ipc.mutex.Lock()
+	defer ipc.mutex.Unlock()
+
+	err = ipc.sendMessage(msgtype, payload)
+	if err != nil {
+		return err
+	}
+
+	replytype, raw, err := ipc.readMessage()
+	if err != nil {
+		return err
+	}
+
+	if replytype != msgtype {
+		return fmt.Errorf("expected reply of type %d, got %d", msgtype, replytype)
+	}
+
+	return json.Unmarshal(raw, reply)
+}
+
+/*
+##############################################################
+# Section: Messages
+##############################################################
+*/
+
+// Runs one or more (separated by ; or ,) sway commands
+func (ipc *IPCConnection) RunCommand(command string) (results []CommandResult, err error) {
+	err = ipc.request(IPC_RUN_COMMAND, []byte(command), &results)
+	if err != nil {
+		return results, err
+	}
+
+	for _, result := range results {
+		if !result.Success {
+			return results, fmt.Errorf("sway command %q failed: %s", command, result.Error)
+		}
+	}
+
+	return results, nil
+}
+
+func (ipc *IPCConnection) GetWorkspaces() (workspaces []Workspace, err error) {
+	err = ipc.request(IPC_GET_WORKSPACES, nil, &workspaces)
+	return workspaces, err
+}
+
+func (ipc *IPCConnection) GetOutputs() (outputs []Output, err error) {
+	err = ipc.request(IPC_GET_OUTPUTS, nil, &outputs)
+	return outputs, err
+}
+
+func (ipc *IPCConnection) GetTree() (root *Node, err error) {
+	root = &Node{}
+	err = ipc.request(IPC_GET_TREE, nil, root)
+	return root, err
+}
+
+func (ipc *IPCConnection) GetVersion() (version Version, err error) {
+	err = ipc.request(IPC_GET_VERSION, nil, &version)
+	return version, err
+}
+
+// Gets the contents of the config file sway loaded last (without the files it includes)
+func (ipc *IPCConnection) GetConfig() (config string, err error) {
+	var reply struct {
+		Config string `json:"config"`
+	}
+	err = ipc.request(IPC_GET_CONFIG, nil, &reply)
+	return reply.Config, err
+}
+
+// Subscribes to the given events (e.g. "workspace", "window").
+// Afterwards the connection should only be used with NextEvent(),
+// so use a separate connection for everything else.
+func (ipc *IPCConnection) Subscribe(events ...string) (err error) {
+	payload, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+
+	var reply struct {
+		Success bool `json:"success"`
+	}
+
+	err = ipc.request(IPC_SUBSCRIBE, payload, &reply)
+	if err != nil {
+		return err
+	}
+
+	if !reply.Success {
+		return fmt.Errorf("subscribing to %v failed", events)
+	}
+
+	return nil
+}
+
+// Blocks until the next event arrives on a subscribed connection
+func (ipc *IPCConnection) NextEvent() (event IPCEvent, err error) {
+	for {
+		msgtype, payload, err := ipc.readMessage()
+		if err != nil {
+			return event, err
+		}
+
+		// skip anything that is not an event
+		if msgtype&IPC_EVENT_MASK == 0 {
+			continue
+		}
+
+		return IPCEvent{Type: msgtype, Payload: payload}, nil
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+// A message as the fake sway sees it
+type fakeMessage struct {
+	msgtype uint32
+	payload string
+}
+
+// Starts a fake sway on a unix socket. For every message it gets, it sends
+// what reply returns (several messages, e.g. a reply and events).
+// The messages it got are sent to the returned channel.
+func startFakeSway(t *testing.T, reply func(message fakeMessage) []fakeMessage) (path string, received chan fakeMessage) {
+	t.Helper()
+
+	path = filepath.Join(t.TempDir(), "sway-ipc.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received = make(chan fakeMessage, 16)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			header := make([]byte, IPC_HEADER_SIZE)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+			if string(header[:len(IPC_MAGIC)]) != IPC_MAGIC {
+				t.Errorf("invalid magic %q", header[:len(IPC_MAGIC)])
+				return
+			}
+
+			length := native_endian.Uint32(header[len(IPC_MAGIC):])
+			payload := make([]byte, length)
+			if _, err := io.ReadFull(conn, payload); err != nil {
+				t.Errorf("reading payload of %d bytes: %v", length, err)
+				return
+			}
+
+			message := fakeMessage{native_endian.Uint32(header[len(IPC_MAGIC)+4:]), string(payload)}
+			received <- message
+
+			for _, answer := range reply(message) {
+				conn.Write(encodeFakeMessage(answer))
+			}
+		}
+	}()
+
+	return path, received
+}
+
+// Frames a message like sway does
+func encodeFakeMessage(message fakeMessage) []byte {
+	data := make([]byte, IPC_HEADER_SIZE)
+	copy(data, IPC_MAGIC)
+	native_endian.PutUint32(data[len(IPC_MAGIC):], uint32(len(message.payload)))
+	native_endian.PutUint32(data[len(IPC_MAGIC)+4:], message.msgtype)
+	return append(data, message.payload...)
+}
+
+func TestIPCHeaderSize(t *testing.T) {
+	// "i3-ipc", payload length and payload type
+	if IPC_HEADER_SIZE != 14 {
+		t.Errorf("IPC_HEADER_SIZE = %d, want 14", IPC_HEADER_SIZE)
+	}
+}
+
+func TestIPCFraming(t *testing.T) {
+	path, received := startFakeSway(t, func(message fakeMessage) []fakeMessage {
+		return []fakeMessage{{message.msgtype, `[{"success":true}]`}}
+	})
+
+	ipc, err := ConnectIPCPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ipc.Close()
+
+	_, err = ipc.RunCommand("workspace 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := <-received
+	want := fakeMessage{IPC_RUN_COMMAND, "workspace 2"}
+	if got != want {
+		t.Errorf("sway got %+v, want %+v", got, want)
+	}
+}
+
+func TestIPCGetWorkspaces(t *testing.T) {
+	path, received := startFakeSway(t, func(message fakeMessage) []fakeMessage {
+		return []fakeMessage{{IPC_GET_WORKSPACES, `[
+			{"num": 1, "name": "1", "visible": true, "focused": true, "output": "DP-1",
+			 "rect": {"x": 0, "y": 0, "width": 1920, "height": 1080}},
+			{"num": 2, "name": "2: web", "urgent": true, "output": "DP-1"}
+		]`}}
+	})
+
+	ipc, err := ConnectIPCPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ipc.Close()
+
+	workspaces, err := ipc.GetWorkspaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if message := <-received; message.msgtype != IPC_GET_WORKSPACES || message.payload != "" {
+		t.Errorf("sway got %+v, want an empty GET_WORKSPACES", message)
+	}
+
+	if len(workspaces) != 2 {
+		t.Fatalf("got %d workspaces, want 2", len(workspaces))
+	}
+	want := Workspace{Num: 1, Name: "1", Visible: true, Focused: true, Output: "DP-1", Rect: IPCRect{0, 0, 1920, 1080}}
+	if workspaces[0] != want {
+		t.Errorf("workspace 0 = %+v, want %+v", workspaces[0], want)
+	}
+	if workspaces[1].Name != "2: web" || !workspaces[1].Urgent || workspaces[1].Focused {
+		t.Errorf("workspace 1 = %+v", workspaces[1])
+	}
+}
+
+func TestIPCGetVersion(t *testing.T) {
+	path, _ := startFakeSway(t, func(message fakeMessage) []fakeMessage {
+		return []fakeMessage{{IPC_GET_VERSION, `{"major": 1, "minor": 8, "patch": 1,
+			"human_readable": "1.8.1", "loaded_config_file_name": "/etc/sway/config"}`}}
+	})
+
+	ipc, err := ConnectIPCPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ipc.Close()
+
+	version, err := ipc.GetVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Version{1, 8, 1, "1.8.1", "/etc/sway/config"}
+	if version != want {
+		t.Errorf("version = %+v, want %+v", version, want)
+	}
+}
+
+func TestIPCReplyOfOtherType(t *testing.T) {
+	path, _ := startFakeSway(t, func(message fakeMessage) []fakeMessage {
+		return []fakeMessage{{IPC_GET_TREE, `{}`}}
+	})
+
+	ipc, err := ConnectIPCPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ipc.Close()
+
+	_, err = ipc.GetVersion()
+	if err == nil {
+		t.Error("a reply of another type was accepted")
+	}
+}
+
+func TestIPCSubscribe(t *testing.T) {
+	path, received := startFakeSway(t, func(message fakeMessage) []fakeMessage {
+		// the reply, something that is not an event and then the events
+		return []fakeMessage{
+			{IPC_SUBSCRIBE, `{"success": true}`},
+			{IPC_GET_VERSION, `{}`},
+			{IPC_EVENT_WORKSPACE, `{"change": "focus", "current": {"name": "2", "type": "workspace"}, "old": {"name": "1"}}`},
+			{IPC_EVENT_WINDOW, `{"change": "new", "container": {"id": 42, "app_id": "foot"}}`},
+		}
+	})
+
+	ipc, err := ConnectIPCPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ipc.Close()
+
+	err = ipc.Subscribe("workspace", "window")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := <-received
+	if message.msgtype != IPC_SUBSCRIBE || message.payload != `["workspace","window"]` {
+		t.Errorf("sway got %+v", message)
+	}
+
+	event, err := ipc.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Type&IPC_EVENT_MASK == 0 {
+		t.Errorf("event type %#x does not have bit 31 set", event.Type)
+	}
+
+	wevent, err := event.Workspace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wevent.Change != "focus" || wevent.Current.Name != "2" || wevent.Old.Name != "1" {
+		t.Errorf("workspace event = %+v", wevent)
+	}
+	if _, err := event.Window(); err == nil {
+		t.Error("a workspace event was decoded as a window event")
+	}
+
+	event, err = ipc.NextEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	window, err := event.Window()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if window.Change != "new" || window.Container.ID != 42 || window.Container.AppID != "foot" {
+		t.Errorf("window event = %+v", window)
+	}
+}
+
+func TestIPCSubscribeFailed(t *testing.T) {
+	path, _ := startFakeSway(t, func(message fakeMessage) []fakeMessage {
+		return []fakeMessage{{IPC_SUBSCRIBE, `{"success": false}`}}
+	})
+
+	ipc, err := ConnectIPCPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ipc.Close()
+
+	if err := ipc.Subscribe("nonsense"); err == nil {
+		t.Error("a failed subscription was accepted")
+	}
+}
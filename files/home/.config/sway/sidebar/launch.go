@@ -0,0 +1,332 @@
+package main
+
+/*
+##############################################################
+# Section: Imports
+##############################################################
+*/
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+/*
+######################################################################################################
+######################################################################################################
+## Chapter: Launching																				##
+######################################################################################################
+######################################################################################################
+*/
+
+/*
+##############################################################
+# Section: Constants & Fields
+##############################################################
+*/
+
+// The terminal used for entries with Terminal=true (see the Terminal key of the config file).
+// The command line of the program is appended as a single, shell quoted argument.
+var TERMINAL_COMMAND = []string{"termite", "-e"}
+
+/*
+##############################################################
+# Section: Launching
+##############################################################
+*/
+
+// Launches the program of a desktop file, opening the given files or URLs (if any)
+func launchDesktopFile(path string, targets ...string) (err error) {
+	entry, err := parseDesktopFile(path)
+	if err != nil {
+		if _, ok := err.(DesktopEntryErrors); !ok || entry.Main() == nil {
+			return err
+		}
+	}
+
+	return launchDesktopEntry(entry, nil, targets)
+}
+
+// Launches a desktop entry. If action is not nil, its Exec key is used instead of the main one.
+func launchDesktopEntry(entry *DesktopEntry, action *DesktopGroup, targets []string) (err error) {
+	main := entry.Main()
+	if main == nil {
+		return errors.New("not a desktop entry")
+	}
+
+	if entry.Type() != "Application" {
+		return fmt.Errorf("cannot launch entries of type %q", entry.Type())
+	}
+
+	// TryExec tells us if the program is installed at all
+	if tryexec := entry.TryExec(); tryexec != "" {
+		if _, err := exec.LookPath(tryexec); err != nil {
+			return fmt.Errorf("%s is not installed: %v", entry.Name(), err)
+		}
+	}
+
+	commands, err := getLaunchCommands(entry, action, targets)
+	if err != nil {
+		return err
+	}
+
+	for _, command := range commands {
+		err = startDetached(command, entry.Path())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Gets the command lines that launch a desktop entry (or one of its actions) with the given targets,
+// already wrapped into the terminal for entries with Terminal=true
+func getLaunchCommands(entry *DesktopEntry, action *DesktopGroup, targets []string) (commands [][]string, err error) {
+	group := entry.Main()
+	if action != nil {
+		group = action
+	}
+
+	argv, err := splitExec(group.String("Exec"))
+	if err != nil {
+		return nil, err
+	}
+
+	commands, err = expandFieldCodes(argv, entry, targets)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Terminal() {
+		for i, command := range commands {
+			commands[i] = append(append([]string{}, TERMINAL_COMMAND...), shellJoin(command))
+		}
+	}
+
+	return commands, nil
+}
+
+// Starts a program in its own session, so it is not killed together with the sidebar
+func startDetached(argv []string, dir string) (err error) {
+	if len(argv) == 0 {
+		return errors.New("empty command")
+	}
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = dir
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+
+	// stdin, stdout and stderr are left nil, which connects them to /dev/null
+
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	// reap the child if it exits before we do
+	go cmd.Wait()
+
+	return nil
+}
+
+/*
+##############################################################
+# Section: Exec key
+##############################################################
+*/
+
+// Splits the (already unescaped) Exec value into arguments by the quoting rules of the spec.
+// Inside of double quotes, \" \` \$ and \\ are escapes for the character after the backslash.
+func splitExec(exec string) (argv []string, err error) {
+	var arg strings.Builder
+	in_arg := false
+	quoted := false
+
+	for i := 0; i < len(exec); i++ {
+		c := exec[i]
+
+		switch {
+		case quoted && c == '\\':
+			if i+1 == len(exec) || !strings.ContainsRune("\"`$\\", rune(exec[i+1])) {
+				return nil, fmt.Errorf("invalid escape in quoted Exec argument: %q", exec)
+			}
+			i++
+			arg.WriteByte(exec[i])
+		case c == '"':
+			quoted = !quoted
+			in_arg = true
+		case !quoted && (c == ' ' || c == '\t' || c == '\n'):
+			if in_arg {
+				argv = append(argv, arg.String())
+				arg.Reset()
+				in_arg = false
+			}
+		default:
+			arg.WriteByte(c)
+			in_arg = true
+		}
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in Exec: %q", exec)
+	}
+
+	if in_arg {
+		argv = append(argv, arg.String())
+	}
+
+	if len(argv) == 0 {
+		return nil, errors.New("empty Exec")
+	}
+
+	return argv, nil
+}
+
+// Expands the field codes of an Exec command line.
+// Since %f and %u only take a single target, they can result in more than one command.
+func expandFieldCodes(argv []string, entry *DesktopEntry, targets []string) (commands [][]string, err error) {
+	single := false
+	for _, arg := range argv {
+		for _, code := range getFieldCodes(arg) {
+			if code == 'f' || code == 'u' {
+				single = true
+			}
+		}
+	}
+
+	// one command per target, or one command for all of them
+	runs := [][]string{targets}
+	if single && len(targets) > 1 {
+		runs = nil
+		for _, target := range targets {
+			runs = append(runs, []string{target})
+		}
+	}
+
+	for _, run := range runs {
+		var command []string
+
+		for _, arg := range argv {
+			// codes that expand to any number of arguments have to stand alone
+			switch arg {
+			case "%F":
+				for _, target := range run {
+					command = append(command, targetToFile(target))
+				}
+				continue
+			case "%U":
+				command = append(command, run...)
+				continue
+			case "%i":
+				if icon := entry.Icon(); icon != "" {
+					command = append(command, "--icon", icon)
+				}
+				continue
+			}
+
+			expanded, err := expandFieldCodesInArg(arg, entry, run)
+			if err != nil {
+				return nil, err
+			}
+
+			// a lone %f or %u without a target disappears
+			if expanded == "" && (arg == "%f" || arg == "%u") {
+				continue
+			}
+
+			command = append(command, expanded)
+		}
+
+		commands = append(commands, command)
+	}
+
+	return commands, nil
+}
+
+// Gets the letters of the field codes in an argument, in order.
+// They are read the same way as expandFieldCodesInArg does, so %% is not the start of one.
+func getFieldCodes(arg string) (codes []byte) {
+	for i := 0; i+1 < len(arg); i++ {
+		if arg[i] != '%' {
+			continue
+		}
+
+		i++
+		if arg[i] != '%' {
+			codes = append(codes, arg[i])
+		}
+	}
+
+	return codes
+}
+
+// Expands the field codes allowed inside of an argument
+func expandFieldCodesInArg(arg string, entry *DesktopEntry, targets []string) (expanded string, err error) {
+	var builder strings.Builder
+
+	for i := 0; i < len(arg); i++ {
+		if arg[i] != '%' {
+			builder.WriteByte(arg[i])
+			continue
+		}
+
+		if i+1 == len(arg) {
+			return "", fmt.Errorf("incomplete field code in %q", arg)
+		}
+
+		i++
+		switch arg[i] {
+		case '%':
+			builder.WriteByte('%')
+		case 'f':
+			if len(targets) > 0 {
+				builder.WriteString(targetToFile(targets[0]))
+			}
+		case 'u':
+			if len(targets) > 0 {
+				builder.WriteString(targets[0])
+			}
+		case 'c':
+			builder.WriteString(entry.Name())
+		case 'k':
+			builder.WriteString(entry.File)
+		case 'd', 'D', 'n', 'N', 'v', 'm':
+			// deprecated, these are removed
+		case 'F', 'U', 'i':
+			return "", fmt.Errorf("field code %%%c must be a separate argument in %q", arg[i], arg)
+		default:
+			return "", fmt.Errorf("unknown field code %%%c in %q", arg[i], arg)
+		}
+	}
+
+	return builder.String(), nil
+}
+
+// Converts file:// URLs into paths, everything else is left alone
+func targetToFile(target string) (path string) {
+	parsed, err := url.Parse(target)
+	if err != nil || parsed.Scheme != "file" {
+		return target
+	}
+	return parsed.Path
+}
+
+// Joins arguments into a command line for sh, quoting where necessary
+func shellJoin(argv []string) (line string) {
+	quoted := make([]string, len(argv))
+
+	for i, arg := range argv {
+		if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:,+@%") == "" {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+		}
+	}
+
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitExec(t *testing.T) {
+	tests := []struct {
+		exec string
+		want []string
+	}{
+		{"firefox", []string{"firefox"}},
+		{"firefox  --new-window\t%u", []string{"firefox", "--new-window", "%u"}},
+		{`"/opt/My App/app" --flag`, []string{"/opt/My App/app", "--flag"}},
+		{`sh -c "echo \"hi\" \$HOME \` + "`" + `x\` + "`" + ` \\"`, []string{"sh", "-c", `echo "hi" $HOME ` + "`x`" + ` \`}},
+		{`prefix"quoted part"suffix`, []string{"prefixquoted partsuffix"}},
+		{`app ""`, []string{"app", ""}},
+		{`app 'single'`, []string{"app", "'single'"}},
+	}
+
+	for _, test := range tests {
+		got, err := splitExec(test.exec)
+		if err != nil {
+			t.Errorf("splitExec(%q): %v", test.exec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitExec(%q) = %q, want %q", test.exec, got, test.want)
+		}
+	}
+}
+
+func TestSplitExecErrors(t *testing.T) {
+	for _, exec := range []string{``, `   `, `app "unterminated`, `app "\n"`, `app "end\`} {
+		if argv, err := splitExec(exec); err == nil {
+			t.Errorf("splitExec(%q) = %q, want an error", exec, argv)
+		}
+	}
+}
+
+func TestExpandFieldCodes(t *testing.T) {
+	entry := parseTestEntry(t, "[Desktop Entry]\nType=Application\nName=My App\nIcon=my-app\n")
+	entry.File = "/usr/share/applications/my-app.desktop"
+
+	two := []string{"file:///tmp/a%20b.txt", "https://example.com/"}
+
+	tests := []struct {
+		exec    string
+		targets []string
+		want    [][]string
+	}{
+		// one command for all targets
+		{"app %F", two, [][]string{{"app", "/tmp/a b.txt", "https://example.com/"}}},
+		{"app %U", two, [][]string{{"app", "file:///tmp/a%20b.txt", "https://example.com/"}}},
+		{"app %F", nil, [][]string{{"app"}}},
+
+		// one command per target
+		{"app %f", two, [][]string{{"app", "/tmp/a b.txt"}, {"app", "https://example.com/"}}},
+		{"app --open=%u", two, [][]string{{"app", "--open=file:///tmp/a%20b.txt"}, {"app", "--open=https://example.com/"}}},
+		{"app %f", nil, [][]string{{"app"}}},
+		{"app --open=%u", nil, [][]string{{"app", "--open="}}},
+
+		// information about the entry
+		{"app %i", nil, [][]string{{"app", "--icon", "my-app"}}},
+		{"app --name=%c", nil, [][]string{{"app", "--name=My App"}}},
+		{"app %k", nil, [][]string{{"app", "/usr/share/applications/my-app.desktop"}}},
+
+		// escaped percent signs are not field codes
+		{"printf %%f %F", two, [][]string{{"printf", "%f", "/tmp/a b.txt", "https://example.com/"}}},
+		{"app 100%%", nil, [][]string{{"app", "100%"}}},
+
+		// deprecated codes disappear
+		{"app %d%D%n%N%v%m x", nil, [][]string{{"app", "", "x"}}},
+	}
+
+	for _, test := range tests {
+		argv, err := splitExec(test.exec)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := expandFieldCodes(argv, entry, test.targets)
+		if err != nil {
+			t.Errorf("%q: %v", test.exec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q with %q = %q, want %q", test.exec, test.targets, got, test.want)
+		}
+	}
+}
+
+func TestExpandFieldCodesErrors(t *testing.T) {
+	entry := parseTestEntry(t, "[Desktop Entry]\nType=Application\nName=App\n")
+
+	for _, exec := range []string{"app --files=%F", "app x%U", "app %i%c", "app %x", "app 100%"} {
+		argv, err := splitExec(exec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if commands, err := expandFieldCodes(argv, entry, nil); err == nil {
+			t.Errorf("%q expanded to %q, want an error", exec, commands)
+		}
+	}
+}
+
+func TestGetFieldCodes(t *testing.T) {
+	tests := map[string]string{
+		"%f":       "f",
+		"%%f":      "",
+		"%%%f":     "f",
+		"a%ub%c":   "uc",
+		"100%":     "",
+		"%%%%u%U%": "U",
+	}
+
+	for arg, want := range tests {
+		if got := string(getFieldCodes(arg)); got != want {
+			t.Errorf("getFieldCodes(%q) = %q, want %q", arg, got, want)
+		}
+	}
+}
+
+func TestGetLaunchCommands(t *testing.T) {
+	old_terminal := TERMINAL_COMMAND
+	TERMINAL_COMMAND = []string{"foot", "-e"}
+	t.Cleanup(func() { TERMINAL_COMMAND = old_terminal })
+
+	entry := parseTestEntry(t, `[Desktop Entry]
+Type=Application
+Name=Editor
+Exec=vim %f
+Terminal=true
+Actions=diff;
+
+[Desktop Action diff]
+Name=Compare
+Exec=vimdiff %F
+`)
+
+	// the command line is a single argument of the terminal, quoted for sh
+	commands, err := getLaunchCommands(entry, nil, []string{"/tmp/it's here.txt", "/tmp/b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"foot", "-e", `vim '/tmp/it'\''s here.txt'`},
+		{"foot", "-e", "vim /tmp/b"},
+	}
+	if !reflect.DeepEqual(commands, want) {
+		t.Errorf("got %q, want %q", commands, want)
+	}
+
+	// actions have their own Exec
+	commands, err = getLaunchCommands(entry, entry.Actions()[0], []string{"/tmp/a", "/tmp/b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = [][]string{{"foot", "-e", "vimdiff /tmp/a /tmp/b"}}
+	if !reflect.DeepEqual(commands, want) {
+		t.Errorf("got %q for the action, want %q", commands, want)
+	}
+
+	// without Terminal=true, nothing is wrapped
+	plain := parseTestEntry(t, "[Desktop Entry]\nType=Application\nName=Files\nExec=nautilus %U\n")
+	commands, err = getLaunchCommands(plain, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(commands, [][]string{{"nautilus"}}) {
+		t.Errorf("got %q without a terminal", commands)
+	}
+}
+
+func TestShellJoin(t *testing.T) {
+	tests := []struct {
+		argv []string
+		want string
+	}{
+		{[]string{"ls", "-la", "/tmp/x_y.z"}, "ls -la /tmp/x_y.z"},
+		{[]string{"echo", "a b", ""}, "echo 'a b' ''"},
+		{[]string{"echo", "it's", "$HOME"}, `echo 'it'\''s' '$HOME'`},
+	}
+
+	for _, test := range tests {
+		if got := shellJoin(test.argv); got != test.want {
+			t.Errorf("shellJoin(%q) = %s, want %s", test.argv, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,432 @@
+package main
+
+/*
+######################################################################################################
+######################################################################################################
+## Chapter: Layout																					##
+######################################################################################################
+######################################################################################################
+*/
+
+/*
+##############################################################
+# Section: Constants & Fields
+##############################################################
+*/
+
+// Stretches an item over its whole cell (the other alignments keep its measured size)
+const FILL Align = 3
+
+// The height of the bars between list entries
+const SEPARATOR_HEIGHT = 4
+
+// Space around (margins) or inside of (padding) an item, in pixels
+type Insets struct {
+	top    int32
+	right  int32
+	bottom int32
+	left   int32
+}
+
+// Gets insets with the same space on every side
+func EvenInsets(space int32) Insets {
+	return Insets{space, space, space, space}
+}
+
+// How a layout places an item
+type LayoutParams struct {
+	margin Insets
+
+	// limits of the size (without the margins), 0 means no limit
+	min Vector
+	max Vector
+
+	// the share of the space left over in a box (0 keeps the measured size)
+	flex float32
+
+	// where the item goes in its cell if it does not fill it
+	halign Align
+	valign Align
+}
+
+// The params of items added without any
+var DEFAULT_LAYOUT_PARAMS = LayoutParams{halign: FILL, valign: FILL}
+
+// Places the items of a container, in their order
+type Layout interface {
+	// Sets the positions and sizes of the items of the container
+	Arrange(cont *Container)
+
+	// Gets the size the container needs to show its items at their measured size
+	Measure(cont *Container) Vector
+}
+
+// Items that know how much space they need (e.g. for their text).
+// Layouts give other items no space, unless their params ask for it.
+type Measurable interface {
+	Measure() Vector
+}
+
+// The items stacked from top to bottom
+type VBox struct {
+	padding Insets
+	spacing int32
+
+	// where the items go if none of them takes the space left over
+	align Align
+}
+
+// The items next to each other from left to right
+type HBox struct {
+	padding Insets
+	spacing int32
+
+	// where the items go if none of them takes the space left over
+	align Align
+}
+
+// The items in rows of equally wide cells
+type Grid struct {
+	padding Insets
+	spacing int32
+	columns int
+
+	// the height of every row (0 makes each row as high as its highest item)
+	row_height int32
+}
+
+// The items on top of each other, each taking the whole container
+type Stack struct {
+	padding Insets
+}
+
+/*
+##############################################################
+# Section: Boxes
+##############################################################
+*/
+
+func (box *VBox) Arrange(cont *Container) {
+	arrangeBox(cont, box.padding, box.spacing, box.align, true)
+}
+
+func (box *VBox) Measure(cont *Container) Vector {
+	return measureBox(cont, box.padding, box.spacing, true)
+}
+
+func (box *HBox) Arrange(cont *Container) {
+	arrangeBox(cont, box.padding, box.spacing, box.align, false)
+}
+
+func (box *HBox) Measure(cont *Container) Vector {
+	return measureBox(cont, box.padding, box.spacing, false)
+}
+
+// Places the items of a box. Vertical boxes are handled as horizontal ones with x and y swapped.
+func arrangeBox(cont *Container, padding Insets, spacing int32, align Align, vertical bool) {
+	children := cont.children
+	if len(children) == 0 {
+		return
+	}
+
+	padding = padding.swapped(vertical)
+	size := cont.size.swapped(vertical)
+	inner := Vector{size.x - padding.left - padding.right, size.y - padding.top - padding.bottom}
+
+	// the width of each cell, with the margins
+	widths := make([]int32, len(children))
+	used := spacing * int32(len(children)-1)
+	var flex_total float32
+
+	for i, child := range children {
+		params := child.params.swapped(vertical)
+
+		width := params.clamp(measureItem(child.item).swapped(vertical)).x
+		if params.flex > 0 {
+			width = params.min.x
+			flex_total += params.flex
+		}
+
+		widths[i] = width + params.margin.left + params.margin.right
+		used += widths[i]
+	}
+
+	// the flexible items share what is left over
+	free := inner.x - used
+	if free > 0 && flex_total > 0 {
+		for i, child := range children {
+			params := child.params.swapped(vertical)
+			if params.flex <= 0 {
+				continue
+			}
+
+			extra := int32(float32(free) * params.flex / flex_total)
+			if params.max.x > 0 && widths[i]+extra > params.max.x+params.margin.left+params.margin.right {
+				extra = params.max.x + params.margin.left + params.margin.right - widths[i]
+			}
+
+			widths[i] += extra
+			used += extra
+		}
+		free = inner.x - used
+	}
+
+	x := padding.left
+	if free > 0 {
+		switch align {
+		case CENTER:
+			x += free / 2
+		case RIGHT:
+			x += free
+		}
+	}
+
+	for i, child := range children {
+		cell_position := Vector{x, padding.top}.swapped(vertical)
+		cell_size := Vector{widths[i], inner.y}.swapped(vertical)
+		placeItem(child.item, child.params, cell_position, cell_size)
+
+		x += widths[i] + spacing
+	}
+}
+
+// Gets the size of a box with all items at their measured size
+func measureBox(cont *Container, padding Insets, spacing int32, vertical bool) (size Vector) {
+	padding = padding.swapped(vertical)
+
+	for i, child := range cont.children {
+		params := child.params.swapped(vertical)
+		measured := params.clamp(measureItem(child.item).swapped(vertical))
+
+		if i > 0 {
+			size.x += spacing
+		}
+		size.x += measured.x + params.margin.left + params.margin.right
+
+		if height := measured.y + params.margin.top + params.margin.bottom; height > size.y {
+			size.y = height
+		}
+	}
+
+	size.x += padding.left + padding.right
+	size.y += padding.top + padding.bottom
+
+	return size.swapped(vertical)
+}
+
+/*
+##############################################################
+# Section: Grid & Stack
+##############################################################
+*/
+
+func (grid *Grid) Arrange(cont *Container) {
+	columns, rows := grid.dimensions(cont)
+	if rows == 0 {
+		return
+	}
+
+	inner_width := cont.size.x - grid.padding.left - grid.padding.right
+	cell_width := (inner_width - grid.spacing*int32(columns-1)) / int32(columns)
+
+	heights := grid.rowHeights(cont, columns, rows)
+
+	y := grid.padding.top
+	for row := 0; row < rows; row++ {
+		for column := 0; column < columns; column++ {
+			i := row*columns + column
+			if i >= len(cont.children) {
+				break
+			}
+
+			child := cont.children[i]
+			cell_position := Vector{grid.padding.left + int32(column)*(cell_width+grid.spacing), y}
+			placeItem(child.item, child.params, cell_position, Vector{cell_width, heights[row]})
+		}
+
+		y += heights[row] + grid.spacing
+	}
+}
+
+func (grid *Grid) Measure(cont *Container) (size Vector) {
+	columns, rows := grid.dimensions(cont)
+	if rows == 0 {
+		return Vector{grid.padding.left + grid.padding.right, grid.padding.top + grid.padding.bottom}
+	}
+
+	// every column is as wide as the widest item
+	var cell_width int32
+	for _, child := range cont.children {
+		params := child.params
+		if width := params.clamp(measureItem(child.item)).x + params.margin.left + params.margin.right; width > cell_width {
+			cell_width = width
+		}
+	}
+
+	size.x = cell_width*int32(columns) + grid.spacing*int32(columns-1) + grid.padding.left + grid.padding.right
+	for _, height := range grid.rowHeights(cont, columns, rows) {
+		size.y += height
+	}
+	size.y += grid.spacing*int32(rows-1) + grid.padding.top + grid.padding.bottom
+
+	return size
+}
+
+// Gets the number of columns and rows
+func (grid *Grid) dimensions(cont *Container) (columns int, rows int) {
+	columns = grid.columns
+	if columns < 1 {
+		columns = 1
+	}
+	rows = (len(cont.children) + columns - 1) / columns
+	return columns, rows
+}
+
+// Gets the height of each row (with the margins of the items)
+func (grid *Grid) rowHeights(cont *Container, columns int, rows int) (heights []int32) {
+	heights = make([]int32, rows)
+
+	for i, child := range cont.children {
+		row := i / columns
+		if grid.row_height > 0 {
+			heights[row] = grid.row_height
+			continue
+		}
+
+		params := child.params
+		if height := params.clamp(measureItem(child.item)).y + params.margin.top + params.margin.bottom; height > heights[row] {
+			heights[row] = height
+		}
+	}
+
+	return heights
+}
+
+func (stack *Stack) Arrange(cont *Container) {
+	cell_position := Vector{stack.padding.left, stack.padding.top}
+	cell_size := Vector{cont.size.x - stack.padding.left - stack.padding.right, cont.size.y - stack.padding.top - stack.padding.bottom}
+
+	for _, child := range cont.children {
+		placeItem(child.item, child.params, cell_position, cell_size)
+	}
+}
+
+func (stack *Stack) Measure(cont *Container) (size Vector) {
+	for _, child := range cont.children {
+		params := child.params
+		measured := params.clamp(measureItem(child.item))
+
+		if width := measured.x + params.margin.left + params.margin.right; width > size.x {
+			size.x = width
+		}
+		if height := measured.y + params.margin.top + params.margin.bottom; height > size.y {
+			size.y = height
+		}
+	}
+
+	size.x += stack.padding.left + stack.padding.right
+	size.y += stack.padding.top + stack.padding.bottom
+
+	return size
+}
+
+/*
+##############################################################
+# Section: Helpers
+##############################################################
+*/
+
+// Gets the measured size of an item (0x0 if it can not be measured)
+func measureItem(item Item) (size Vector) {
+	if measurable, ok := item.(Measurable); ok {
+		return measurable.Measure()
+	}
+	return Vector{0, 0}
+}
+
+// Places an item in a cell, by the margins and alignment of its params
+func placeItem(item Item, params LayoutParams, cell_position Vector, cell_size Vector) {
+	// the margins are part of the cell
+	cell_position.x += params.margin.left
+	cell_position.y += params.margin.top
+	cell_size.x -= params.margin.left + params.margin.right
+	cell_size.y -= params.margin.top + params.margin.bottom
+
+	measured := params.clamp(measureItem(item))
+
+	place := func(align Align, cell_start int32, cell_length int32, min int32, max int32, measured int32) (start int32, length int32) {
+		length = measured
+		if align == FILL {
+			length = clampLength(cell_length, min, max)
+		}
+		if length > cell_length {
+			length = cell_length
+		}
+		if length < 0 {
+			length = 0
+		}
+
+		switch align {
+		case CENTER:
+			return cell_start + (cell_length-length)/2, length
+		case RIGHT:
+			return cell_start + cell_length - length, length
+		}
+		return cell_start, length
+	}
+
+	var position, size Vector
+	position.x, size.x = place(params.halign, cell_position.x, cell_size.x, params.min.x, params.max.x, measured.x)
+	position.y, size.y = place(params.valign, cell_position.y, cell_size.y, params.min.y, params.max.y, measured.y)
+
+	// only changes make the item dirty
+	if item.GetPosition() != position {
+		item.SetPosition(position)
+	}
+	if item.GetSize() != size {
+		item.SetSize(size)
+	}
+}
+
+// Keeps a size within the limits of the params
+func (params LayoutParams) clamp(size Vector) Vector {
+	return Vector{clampLength(size.x, params.min.x, params.max.x), clampLength(size.y, params.min.y, params.max.y)}
+}
+
+// Keeps a length within min and max (if they are not 0)
+func clampLength(length int32, min int32, max int32) int32 {
+	if max > 0 && length > max {
+		length = max
+	}
+	if length < min {
+		length = min
+	}
+	return length
+}
+
+// Swaps x and y if swap is true
+func (vec Vector) swapped(swap bool) Vector {
+	if swap {
+		return Vector{vec.y, vec.x}
+	}
+	return vec
+}
+
+// Swaps top with left and bottom with right if swap is true
+func (insets Insets) swapped(swap bool) Insets {
+	if swap {
+		return Insets{top: insets.left, right: insets.bottom, bottom: insets.right, left: insets.top}
+	}
+	return insets
+}
+
+// Swaps the horizontal and vertical parts of the params if swap is true
+func (params LayoutParams) swapped(swap bool) LayoutParams {
+	if swap {
+		params.margin = params.margin.swapped(true)
+		params.min = params.min.swapped(true)
+		params.max = params.max.swapped(true)
+		params.halign, params.valign = params.valign, params.halign
+	}
+	return params
+}
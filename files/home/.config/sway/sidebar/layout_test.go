@@ -0,0 +1,255 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// An item that needs a fixed size
+type measuredItem struct {
+	Unicolor
+	measured Vector
+}
+
+func (item *measuredItem) Measure() Vector {
+	return item.measured
+}
+
+// An item of a test container: its measured size and how it is placed
+type layoutChild struct {
+	measured Vector
+	params   LayoutParams
+}
+
+// Where an item was placed
+type layoutCell struct {
+	position Vector
+	size     Vector
+}
+
+// Places items with a layout in a container of a size, and gets the cells they were placed in
+// and the size the layout measured for them
+func arrangeTestItems(layout Layout, size Vector, children ...layoutChild) (cells []layoutCell, measured Vector) {
+	cont := &Container{size: size}
+	cont.SetLayout(layout)
+
+	items := make([]*measuredItem, len(children))
+	for i, child := range children {
+		items[i] = &measuredItem{measured: child.measured}
+		cont.AddItemWithParams(string(rune('a'+i)), items[i], child.params)
+	}
+
+	layout.Arrange(cont)
+	for _, item := range items {
+		cells = append(cells, layoutCell{item.GetPosition(), item.GetSize()})
+	}
+
+	return cells, cont.Measure()
+}
+
+func TestBoxLayout(t *testing.T) {
+	tests := []struct {
+		name     string
+		layout   Layout
+		size     Vector
+		children []layoutChild
+		cells    []layoutCell
+		measured Vector
+	}{
+		{
+			"vbox",
+			&VBox{padding: Insets{10, 5, 10, 5}, spacing: 4},
+			Vector{100, 200},
+			[]layoutChild{
+				{Vector{30, 20}, DEFAULT_LAYOUT_PARAMS},
+				{Vector{40, 30}, LayoutParams{margin: EvenInsets(2), halign: RIGHT, valign: FILL}},
+			},
+			// the margins are part of the cell, the items fill the width unless aligned
+			[]layoutCell{{Vector{5, 10}, Vector{90, 20}}, {Vector{53, 36}, Vector{40, 30}}},
+			Vector{54, 78},
+		},
+		{
+			"hbox aligned right",
+			&HBox{spacing: 5, align: RIGHT},
+			Vector{100, 20},
+			[]layoutChild{
+				{Vector{20, 10}, DEFAULT_LAYOUT_PARAMS},
+				{Vector{30, 10}, DEFAULT_LAYOUT_PARAMS},
+			},
+			[]layoutCell{{Vector{45, 0}, Vector{20, 20}}, {Vector{70, 0}, Vector{30, 20}}},
+			Vector{55, 10},
+		},
+		{
+			"vbox centered",
+			&VBox{align: CENTER},
+			Vector{10, 100},
+			[]layoutChild{
+				{Vector{10, 20}, DEFAULT_LAYOUT_PARAMS},
+				{Vector{10, 30}, DEFAULT_LAYOUT_PARAMS},
+			},
+			[]layoutCell{{Vector{0, 25}, Vector{10, 20}}, {Vector{0, 45}, Vector{10, 30}}},
+			Vector{10, 50},
+		},
+		{
+			"empty",
+			&HBox{padding: EvenInsets(3), spacing: 5},
+			Vector{100, 100},
+			nil,
+			nil,
+			Vector{6, 6},
+		},
+	}
+
+	for _, test := range tests {
+		cells, measured := arrangeTestItems(test.layout, test.size, test.children...)
+		if !reflect.DeepEqual(cells, test.cells) {
+			t.Errorf("%s: placed in %v, want %v", test.name, cells, test.cells)
+		}
+		if measured != test.measured {
+			t.Errorf("%s: measured %v, want %v", test.name, measured, test.measured)
+		}
+	}
+}
+
+func TestBoxFlex(t *testing.T) {
+	// the flexible items share what is left over by their flex, ignoring their measured size
+	cells, measured := arrangeTestItems(&HBox{}, Vector{200, 10},
+		layoutChild{Vector{50, 10}, DEFAULT_LAYOUT_PARAMS},
+		layoutChild{Vector{999, 10}, LayoutParams{flex: 1, halign: FILL, valign: FILL}},
+		layoutChild{Vector{999, 10}, LayoutParams{flex: 3, halign: FILL, valign: FILL}},
+	)
+
+	want := []layoutCell{{Vector{0, 0}, Vector{50, 10}}, {Vector{50, 0}, Vector{37, 10}}, {Vector{87, 0}, Vector{112, 10}}}
+	if !reflect.DeepEqual(cells, want) {
+		t.Errorf("placed in %v, want %v", cells, want)
+	}
+
+	// measuring takes the measured sizes
+	if want := (Vector{50 + 999 + 999, 10}); measured != want {
+		t.Errorf("measured %v, want %v", measured, want)
+	}
+
+	// flexible items start at their minimum and stop at their maximum, what they do not take stays free
+	cells, _ = arrangeTestItems(&VBox{}, Vector{10, 200},
+		layoutChild{Vector{10, 999}, LayoutParams{flex: 1, max: Vector{0, 20}, halign: FILL, valign: FILL}},
+		layoutChild{Vector{10, 999}, LayoutParams{flex: 1, min: Vector{0, 10}, halign: FILL, valign: FILL}},
+		layoutChild{Vector{10, 300}, LayoutParams{max: Vector{0, 40}, halign: FILL, valign: FILL}},
+	)
+
+	want = []layoutCell{{Vector{0, 0}, Vector{10, 20}}, {Vector{0, 20}, Vector{10, 85}}, {Vector{0, 105}, Vector{10, 40}}}
+	if !reflect.DeepEqual(cells, want) {
+		t.Errorf("placed in %v with limits, want %v", cells, want)
+	}
+
+	// without space left over, flexible items keep their minimum
+	cells, _ = arrangeTestItems(&HBox{}, Vector{40, 10},
+		layoutChild{Vector{50, 10}, DEFAULT_LAYOUT_PARAMS},
+		layoutChild{Vector{999, 10}, LayoutParams{flex: 1, min: Vector{5, 0}, halign: FILL, valign: FILL}},
+	)
+
+	want = []layoutCell{{Vector{0, 0}, Vector{50, 10}}, {Vector{50, 0}, Vector{5, 10}}}
+	if !reflect.DeepEqual(cells, want) {
+		t.Errorf("placed in %v without space, want %v", cells, want)
+	}
+}
+
+func TestBoxClamp(t *testing.T) {
+	cells, measured := arrangeTestItems(&HBox{}, Vector{100, 40},
+		// filling the height up to the maximum
+		layoutChild{Vector{10, 10}, LayoutParams{max: Vector{0, 30}, halign: FILL, valign: FILL}},
+
+		// the minimum grows the measured size
+		layoutChild{Vector{10, 10}, LayoutParams{min: Vector{25, 16}, halign: FILL, valign: CENTER}},
+
+		// never larger than the cell
+		layoutChild{Vector{10, 60}, LayoutParams{halign: FILL, valign: BOTTOM}},
+	)
+
+	want := []layoutCell{{Vector{0, 0}, Vector{10, 30}}, {Vector{10, 12}, Vector{25, 16}}, {Vector{35, 0}, Vector{10, 40}}}
+	if !reflect.DeepEqual(cells, want) {
+		t.Errorf("placed in %v, want %v", cells, want)
+	}
+	if want := (Vector{45, 60}); measured != want {
+		t.Errorf("measured %v, want %v", measured, want)
+	}
+}
+
+func TestGridLayout(t *testing.T) {
+	children := []layoutChild{
+		{Vector{10, 10}, DEFAULT_LAYOUT_PARAMS},
+		{Vector{20, 15}, DEFAULT_LAYOUT_PARAMS},
+		{Vector{5, 5}, LayoutParams{halign: CENTER, valign: CENTER}},
+		{Vector{30, 8}, LayoutParams{max: Vector{25, 0}, halign: FILL, valign: FILL}},
+		{Vector{12, 12}, DEFAULT_LAYOUT_PARAMS},
+	}
+
+	// equally wide columns fill the width, each row is as high as its highest item
+	cells, measured := arrangeTestItems(&Grid{columns: 3, spacing: 2, padding: EvenInsets(1)}, Vector{100, 100}, children...)
+
+	want := []layoutCell{
+		{Vector{1, 1}, Vector{31, 15}}, {Vector{34, 1}, Vector{31, 15}}, {Vector{80, 6}, Vector{5, 5}},
+		{Vector{1, 18}, Vector{25, 12}}, {Vector{34, 18}, Vector{31, 12}},
+	}
+	if !reflect.DeepEqual(cells, want) {
+		t.Errorf("placed in %v, want %v", cells, want)
+	}
+
+	// measured, every column is as wide as the widest item
+	if want := (Vector{25*3 + 2*2 + 2, 15 + 12 + 2 + 2}); measured != want {
+		t.Errorf("measured %v, want %v", measured, want)
+	}
+
+	// a fixed row height
+	cells, measured = arrangeTestItems(&Grid{columns: 3, row_height: 20}, Vector{90, 100}, children...)
+	if cells[4] != (layoutCell{Vector{30, 20}, Vector{30, 20}}) || measured != (Vector{75, 40}) {
+		t.Errorf("placed the last item in %v and measured %v with rows 20 high", cells[4], measured)
+	}
+
+	// without columns, there is one
+	cells, measured = arrangeTestItems(&Grid{}, Vector{50, 100}, children[:2]...)
+	want = []layoutCell{{Vector{0, 0}, Vector{50, 10}}, {Vector{0, 10}, Vector{50, 15}}}
+	if !reflect.DeepEqual(cells, want) || measured != (Vector{20, 25}) {
+		t.Errorf("placed in %v and measured %v with one column, want %v", cells, measured, want)
+	}
+
+	// empty, only the padding
+	if _, measured := arrangeTestItems(&Grid{columns: 2, padding: EvenInsets(3)}, Vector{50, 100}); measured != (Vector{6, 6}) {
+		t.Errorf("measured %v without items", measured)
+	}
+}
+
+func TestStackLayout(t *testing.T) {
+	cells, measured := arrangeTestItems(&Stack{padding: Insets{1, 2, 3, 4}}, Vector{50, 40},
+		layoutChild{Vector{10, 10}, DEFAULT_LAYOUT_PARAMS},
+		layoutChild{Vector{10, 6}, LayoutParams{margin: Insets{0, 0, 0, 2}, halign: CENTER, valign: BOTTOM}},
+	)
+
+	// every item has the whole container (without the padding) as its cell
+	want := []layoutCell{{Vector{4, 1}, Vector{44, 36}}, {Vector{22, 31}, Vector{10, 6}}}
+	if !reflect.DeepEqual(cells, want) {
+		t.Errorf("placed in %v, want %v", cells, want)
+	}
+	if want := (Vector{12 + 6, 10 + 4}); measured != want {
+		t.Errorf("measured %v, want %v", measured, want)
+	}
+}
+
+func TestNestedLayoutMeasure(t *testing.T) {
+	// a container in a box is measured by its own layout
+	row := &Container{layout: &HBox{spacing: 2}}
+	row.AddItem("a", &measuredItem{measured: Vector{10, 5}})
+	row.AddItem("b", &measuredItem{measured: Vector{20, 8}})
+
+	cont := &Container{size: Vector{100, 100}, layout: &VBox{padding: EvenInsets(1)}}
+	cont.AddItemWithParams("row", row, LayoutParams{halign: LEFT, valign: FILL})
+	cont.AddItem("below", &measuredItem{measured: Vector{4, 4}})
+
+	if measured := cont.Measure(); measured != (Vector{32 + 2, 8 + 4 + 2}) {
+		t.Errorf("measured %v", measured)
+	}
+
+	cont.layout.Arrange(cont)
+	if row.GetPosition() != (Vector{1, 1}) || row.GetSize() != (Vector{32, 8}) {
+		t.Errorf("placed the row at %v with %v", row.GetPosition(), row.GetSize())
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+/*
+##############################################################
+# Section: Imports
+##############################################################
+*/
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/*
+########################
+# Subsection: ListView
+########################
+*/
+
+// Width of the scrollbar and of the selection marker in pixels
+const (
+	SCROLLBAR_WIDTH = 6
+	MARKER_WIDTH    = 4
+)
+
+// Rows scrolled per step of the mouse wheel
+const WHEEL_ROWS = 3
+
+// The data shown by a ListView
+type ListSource interface {
+	Len() int
+}
+
+// Creates the item showing a row of a ListView. The item has to fill size.
+type RowRenderer func(index int, size Vector, selected bool) (row Item, err error)
+
+// A vertical list of equally high rows.
+// Only the visible rows are rendered (and kept), so long lists stay fast.
+// Like TextInput, it gets its events from the window handler.
+type ListView struct {
+	position   Vector
+	size       Vector
+	row_height int32
+
+	source ListSource
+	render RowRenderer
+
+	bgcolor  ColorRole
+	selcolor ColorRole // selection marker
+	barcolor ColorRole // scrollbar thumb
+
+	// called with the selected index when enter is pressed
+	on_activate func(index int)
+
+	selected int
+	top      int // first visible row
+
+	// rendered rows by index
+	rows map[int]Item
+
+	dirty bool
+}
+
+// Tells the list that the source has changed, so all rows are rendered again
+func (list *ListView) Refresh() {
+	list.rows = nil
+	list.Select(list.selected)
+}
+
+// Gets the selected index (-1 if the list is empty)
+func (list *ListView) Selected() (index int) {
+	if list.source == nil || list.source.Len() == 0 {
+		return -1
+	}
+	return list.selected
+}
+
+// Selects a row and scrolls to it
+func (list *ListView) Select(index int) {
+	length := 0
+	if list.source != nil {
+		length = list.source.Len()
+	}
+
+	if index >= length {
+		index = length - 1
+	}
+	if index < 0 {
+		index = 0
+	}
+
+	// the rows show if they are selected
+	if index != list.selected {
+		delete(list.rows, list.selected)
+		delete(list.rows, index)
+	}
+	list.selected = index
+
+	// scroll just enough to show the selected row (once the list has a size)
+	if list.selected < list.top {
+		list.top = list.selected
+	}
+	if visible := list.visibleRows(); visible > 0 && list.selected >= list.top+visible {
+		list.top = list.selected - visible + 1
+	}
+	list.scrollTo(list.top)
+}
+
+// Sets the first visible row, keeping the list filled if possible
+func (list *ListView) scrollTo(top int) {
+	length := 0
+	if list.source != nil {
+		length = list.source.Len()
+	}
+
+	if top > length-list.visibleRows() {
+		top = length - list.visibleRows()
+	}
+	if top < 0 {
+		top = 0
+	}
+	list.top = top
+	list.dirty = true
+}
+
+// Gets the number of rows that fit (completely) into the list
+func (list *ListView) visibleRows() (rows int) {
+	if list.row_height <= 0 {
+		return 0
+	}
+	return int(list.size.y / list.row_height)
+}
+
+// Gets the index of the row at a point of the list (-1 if there is none)
+func (list *ListView) RowAt(point Vector) (index int) {
+	if list.source == nil || list.row_height <= 0 || !point.inside(list.size) {
+		return -1
+	}
+
+	index = list.top + int(point.y/list.row_height)
+	if index >= list.source.Len() {
+		return -1
+	}
+	return index
+}
+
+// Handles keyboard and mouse wheel events. Returns true if the event was used.
+func (list *ListView) HandleEvent(event sdl.Event) (handled bool) {
+	switch ty := event.(type) {
+	case *sdl.KeyboardEvent:
+		if ty.Type != sdl.KEYDOWN {
+			return false
+		}
+
+		page := list.visibleRows() - 1
+		if page < 1 {
+			page = 1
+		}
+
+		switch ty.Keysym.Sym {
+		case sdl.K_UP:
+			list.Select(list.selected - 1)
+		case sdl.K_DOWN:
+			list.Select(list.selected + 1)
+		case sdl.K_PAGEUP:
+			list.Select(list.selected - page)
+		case sdl.K_PAGEDOWN:
+			list.Select(list.selected + page)
+		case sdl.K_HOME:
+			list.Select(0)
+		case sdl.K_END:
+			list.Select(list.source.Len() - 1)
+		case sdl.K_RETURN:
+			if list.on_activate == nil || list.Selected() < 0 {
+				return false
+			}
+			list.on_activate(list.selected)
+		default:
+			return false
+		}
+	case *sdl.MouseWheelEvent:
+		y := ty.Y
+		if ty.Direction == sdl.MOUSEWHEEL_FLIPPED {
+			y = -y
+		}
+
+		// the wheel scrolls without moving the selection
+		list.scrollTo(list.top - int(y)*WHEEL_ROWS)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// Draw the item onto the canvas
+func (list *ListView) Draw(canvas Canvas) (err error) {
+	list.dirty = false
+	canvas.FillRect(Vector{0, 0}, list.size, list.bgcolor.RGB())
+
+	if list.source == nil || list.render == nil || list.row_height <= 0 {
+		return nil
+	}
+
+	length := list.source.Len()
+	row_size := Vector{list.size.x, list.row_height}
+
+	// with a scrollbar, the rows get a little narrower
+	scrollbar := length > list.visibleRows()
+	if scrollbar {
+		row_size.x -= SCROLLBAR_WIDTH
+	}
+	if row_size.x <= 0 {
+		return nil
+	}
+
+	// also draw the partly visible row at the bottom
+	last := list.top + list.visibleRows()
+	if last >= length {
+		last = length - 1
+	}
+
+	rows := make(map[int]Item)
+	for i := list.top; i <= last; i++ {
+		row, ok := list.rows[i]
+		if !ok || row.GetSize() != row_size {
+			row, err = list.render(i, row_size, i == list.selected)
+			if err != nil {
+				return err
+			}
+		}
+		rows[i] = row
+
+		y := int32(i-list.top) * list.row_height
+
+		canvas.Save()
+		canvas.Translate(Vector{0, y})
+		canvas.Clip(Vector{0, 0}, row_size)
+		canvas.FillRect(Vector{0, 0}, row_size, background_color)
+		err = row.Draw(canvas)
+		canvas.Restore()
+		if err != nil {
+			return err
+		}
+
+		if i == list.selected {
+			canvas.FillRect(Vector{0, y}, Vector{MARKER_WIDTH, row_size.y}, list.selcolor.RGB())
+		}
+	}
+
+	// rows that were scrolled out of view are dropped
+	list.rows = rows
+
+	if scrollbar {
+		thumb_height := list.size.y * int32(list.visibleRows()) / int32(length)
+		if thumb_height < SCROLLBAR_WIDTH*2 {
+			thumb_height = SCROLLBAR_WIDTH * 2
+		}
+		thumb_y := (list.size.y - thumb_height) * int32(list.top) / int32(length-list.visibleRows())
+
+		canvas.FillRect(Vector{row_size.x, thumb_y}, Vector{SCROLLBAR_WIDTH, thumb_height}, list.barcolor.RGB())
+	}
+
+	return nil
+}
+
+// Getters and setters
+
+func (list *ListView) GetPosition() (position Vector) {
+	return list.position
+}
+
+func (list *ListView) SetPosition(position Vector) {
+	list.position = position
+	list.dirty = true
+}
+
+func (list *ListView) GetSize() (size Vector) {
+	return list.size
+}
+
+func (list *ListView) SetSize(size Vector) {
+	list.size = size
+	list.scrollTo(list.top)
+}
+
+// Also dirty if a visible row changed
+func (list *ListView) Dirty() bool {
+	if list.dirty {
+		return true
+	}
+	for _, row := range list.rows {
+		if row.Dirty() {
+			return true
+		}
+	}
+	return false
+}
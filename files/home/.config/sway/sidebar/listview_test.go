@@ -0,0 +1,138 @@
+package main
+
+import (
+	"testing"
+)
+
+// A list of n rows
+type testSource int
+
+func (source testSource) Len() int {
+	return int(source)
+}
+
+// Colors the rows by index, so the test can tell them apart
+var test_row_colors = []ColorRole{FOREGROUND, SEPARATOR, URGENT, SELECTION}
+
+func newTestList(rows int) (list *ListView, rendered *[]int) {
+	rendered = &[]int{}
+	list = &ListView{
+		row_height: 10,
+		source:     testSource(rows),
+		render: func(index int, size Vector, selected bool) (Item, error) {
+			*rendered = append(*rendered, index)
+			return &Unicolor{size: size, color: test_row_colors[index%len(test_row_colors)]}, nil
+		},
+		bgcolor:  BACKGROUND,
+		selcolor: ACCENT,
+		barcolor: MUTED,
+	}
+	return list, rendered
+}
+
+func TestListViewDraw(t *testing.T) {
+	useTestTheme(t)
+
+	list, _ := newTestList(10)
+	list.SetSize(Vector{50, 35})
+	list.Select(5)
+	img := drawTestItem(t, list, Vector{50, 35})
+
+	// three rows fit completely, the list scrolled just enough to show the selected one
+	if list.top != 3 {
+		t.Fatalf("the first visible row is %d, want 3", list.top)
+	}
+
+	for i, row := range []int{3, 4, 5, 6} {
+		y := i*10 + 2
+		want := test_row_colors[row%len(test_row_colors)].RGB()
+		if !hasColor(img, 20, y, want) {
+			t.Errorf("row %d at y %d has %v, want row %d", i, y, img.RGBAAt(20, y), row)
+		}
+
+		marked := hasColor(img, 0, y, ACCENT.RGB()) && hasColor(img, MARKER_WIDTH-1, y, ACCENT.RGB())
+		if marked != (row == 5) {
+			t.Errorf("row %d marked: %v", row, marked)
+		}
+	}
+
+	// the rows leave room for the scrollbar
+	if hasColor(img, 50-SCROLLBAR_WIDTH, 34, test_row_colors[6%len(test_row_colors)].RGB()) {
+		t.Error("a row is drawn over the scrollbar")
+	}
+
+	// the thumb is somewhere in the middle
+	thumb := 0
+	for y := 0; y < 35; y++ {
+		if hasColor(img, 50-SCROLLBAR_WIDTH/2, y, MUTED.RGB()) {
+			thumb++
+		}
+	}
+	if thumb == 0 || thumb == 35 || hasColor(img, 50-SCROLLBAR_WIDTH/2, 0, MUTED.RGB()) {
+		t.Errorf("the scrollbar thumb is %d pixels high", thumb)
+	}
+}
+
+func TestListViewWithoutScrollbar(t *testing.T) {
+	useTestTheme(t)
+
+	list, _ := newTestList(2)
+	img := drawTestItem(t, list, Vector{50, 35})
+
+	// the rows take the whole width, below them is the background
+	if !hasColor(img, 49, 2, test_row_colors[0].RGB()) {
+		t.Errorf("the first row ends before the edge of the list: %v", img.RGBAAt(49, 2))
+	}
+	if !hasColor(img, 20, 25, BACKGROUND.RGB()) {
+		t.Errorf("there is something below the rows: %v", img.RGBAAt(20, 25))
+	}
+}
+
+func TestListViewKeepsRows(t *testing.T) {
+	useTestTheme(t)
+
+	list, rendered := newTestList(10)
+	drawTestItem(t, list, Vector{50, 35})
+	if len(*rendered) != 4 {
+		t.Fatalf("rendered rows %v, want only the 4 visible ones", *rendered)
+	}
+
+	// drawing again uses the rows from before, except for the ones that changed their selection
+	*rendered = nil
+	list.Select(1)
+	drawTestItem(t, list, Vector{50, 35})
+	if len(*rendered) != 2 || (*rendered)[0] != 0 || (*rendered)[1] != 1 {
+		t.Errorf("rendered rows %v again, want 0 and 1", *rendered)
+	}
+
+	// refreshing renders all of them again
+	*rendered = nil
+	list.Refresh()
+	drawTestItem(t, list, Vector{50, 35})
+	if len(*rendered) != 4 {
+		t.Errorf("rendered rows %v after a refresh, want 4", *rendered)
+	}
+}
+
+func TestListViewRowAt(t *testing.T) {
+	list, _ := newTestList(10)
+	list.SetSize(Vector{50, 35})
+	list.Select(9)
+
+	tests := []struct {
+		point Vector
+		want  int
+	}{
+		{Vector{5, 0}, 7},
+		{Vector{5, 25}, 9},
+		{Vector{5, 34}, -1}, // below the last row
+		{Vector{60, 5}, -1},
+		{Vector{5, -1}, -1},
+	}
+
+	for _, test := range tests {
+		if got := list.RowAt(test.point); got != test.want {
+			t.Errorf("RowAt(%v) = %d, want %d", test.point, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,898 @@
+package main
+
+/*
+##############################################################
+# Section: Imports
+##############################################################
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/*
+######################################################################################################
+######################################################################################################
+## Chapter: Panels																					##
+######################################################################################################
+######################################################################################################
+*/
+
+/*
+##############################################################
+# Section: Constants & Fields
+##############################################################
+*/
+
+// Panels are windows described by a JSON file, shown with `sidebar open <file>`.
+// <file> is a path, or the name of a file in this subdirectory of the config directory
+// (e.g. panels/tools.json for tools).
+//
+// The file describes the main container:
+//
+//	{
+//		"layout": {"type": "vbox", "spacing": 8},
+//		"items": [
+//			{"type": "label", "text": "Tools", "font": "Title", "halign": "center"},
+//			{"type": "label", "bind": {"type": "command", "command": "date +%H:%M", "interval": 10}},
+//			{"type": "list", "bind": {"type": "desktop-entries"}, "actions": [{"type": "launch"}],
+//				"place": {"flex": 1}}
+//		],
+//		"keys": {"l": [{"type": "run", "command": "swaylock"}, {"type": "close"}]}
+//	}
+//
+// See PanelItemSpec for the items, PanelBindingSpec for where their content can come from
+// and PanelActionSpec for what clicks (and keys) do.
+const PANELS_DIR = "panels"
+
+// The file extension of panels in PANELS_DIR
+const PANEL_EXTENSION = ".json"
+
+// A panel file
+type PanelSpec struct {
+	PanelItemSpec
+
+	// actions run when a character is typed, by the character
+	Keys map[string][]PanelActionSpec `json:"keys"`
+}
+
+// An item of a panel. Type is one of:
+//
+//	container: groups items, placed by its layout
+//	label:     a line of text (text, font, color, halign, valign)
+//	color:     a filled rectangle (color), e.g. a separator
+//	image:     an image file (path) or an icon of the icon theme (icon), size pixels high
+//	list:      rows of text from its binding, actions run on the selected row
+type PanelItemSpec struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+
+	// container
+	Layout *PanelLayoutSpec `json:"layout"`
+	Items  []PanelItemSpec  `json:"items"`
+
+	// label and color. Fonts and colors are role names of the theme (e.g. "Header", "Muted").
+	Text   string `json:"text"`
+	Font   string `json:"font"`
+	Color  string `json:"color"`
+	HAlign string `json:"halign"`
+	VAlign string `json:"valign"`
+
+	// image
+	Path string `json:"path"`
+	Icon string `json:"icon"`
+	Size int32  `json:"size"`
+
+	// list (0 makes the rows one line high)
+	RowHeight int32 `json:"row_height"`
+
+	// where the text of a label or the rows of a list come from
+	Bind *PanelBindingSpec `json:"bind"`
+
+	// run when the item is clicked (lists: when a row is clicked or enter is pressed)
+	Actions []PanelActionSpec `json:"actions"`
+
+	// how the layout of the container places the item
+	Place *PanelPlaceSpec `json:"place"`
+}
+
+// A layout: vbox, hbox, grid or stack (see layout.go).
+// Insets are [all], [vertical, horizontal] or [top, right, bottom, left].
+type PanelLayoutSpec struct {
+	Type      string  `json:"type"`
+	Padding   []int32 `json:"padding"`
+	Spacing   int32   `json:"spacing"`
+	Align     string  `json:"align"`
+	Columns   int     `json:"columns"`
+	RowHeight int32   `json:"row_height"`
+}
+
+// The LayoutParams of an item. Sizes are [width, height].
+type PanelPlaceSpec struct {
+	Margin []int32 `json:"margin"`
+	Min    []int32 `json:"min"`
+	Max    []int32 `json:"max"`
+	Flex   float32 `json:"flex"`
+	HAlign string  `json:"halign"`
+	VAlign string  `json:"valign"`
+}
+
+// Where the content of an item comes from. Type is one of:
+//
+//	command:         the output of a shell command, run again every interval seconds (0: only once).
+//	                 Labels show its first line, lists a row for every line.
+//	desktop-entries: the programs that can be launched (lists only), most used first
+type PanelBindingSpec struct {
+	Type     string  `json:"type"`
+	Command  string  `json:"command"`
+	Interval float64 `json:"interval"`
+}
+
+// Something a click or key does. Type is one of:
+//
+//	run:    runs a shell command
+//	sway:   runs a sway command
+//	close:  closes the panel
+//	launch: launches the selected program of a desktop-entries list
+//
+// In the commands of list actions, {text} is replaced by the text of the selected row (quoted).
+type PanelActionSpec struct {
+	Type    string `json:"type"`
+	Command string `json:"command"`
+}
+
+// The names of alignments in panel files
+var panel_aligns = map[string]Align{
+	"left": LEFT, "top": TOP, "center": CENTER, "right": RIGHT, "bottom": BOTTOM, "fill": FILL,
+}
+
+/*
+##############################################################
+# Section: Loading
+##############################################################
+*/
+
+// Finds a panel file by path, or by name in the panels directory
+func findPanelFile(name string) (path string, err error) {
+	path = expandHomePath(name)
+	if _, err := os.Stat(path); err == nil || strings.ContainsRune(name, filepath.Separator) {
+		return path, err
+	}
+
+	config_dir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	path = filepath.Join(config_dir, PANELS_DIR, name+PANEL_EXTENSION)
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("no panel %q (neither a file nor in %s)", name, filepath.Join(config_dir, PANELS_DIR))
+	}
+
+	return path, nil
+}
+
+// Reads and checks a panel file
+func loadPanelSpec(name string) (spec PanelSpec, err error) {
+	path, err := findPanelFile(name)
+	if err != nil {
+		return spec, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return spec, err
+	}
+
+	// misspelled keys are errors, instead of being ignored
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	err = decoder.Decode(&spec)
+	if err != nil {
+		return spec, fmt.Errorf("%s: %v", path, err)
+	}
+
+	// the file is the main container
+	if spec.Type == "" {
+		spec.Type = "container"
+	}
+
+	err = spec.check("panel")
+	if err == nil && spec.Type != "container" {
+		err = fmt.Errorf("panel: the panel is a container, not a %s", spec.Type)
+	}
+	if err == nil {
+		for key, actions := range spec.Keys {
+			err = checkPanelActions(actions, "keys."+key)
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return spec, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return spec, nil
+}
+
+// Checks an item and the items in it. where tells the user which item it is.
+func (spec *PanelItemSpec) check(where string) (err error) {
+	if spec.Name != "" {
+		where += " (" + spec.Name + ")"
+	}
+
+	switch spec.Type {
+	case "container", "label", "color", "image", "list":
+	default:
+		return fmt.Errorf("%s: unknown type %q, expected container, label, color, image or list", where, spec.Type)
+	}
+
+	if spec.Type != "container" && (spec.Layout != nil || len(spec.Items) > 0) {
+		return fmt.Errorf("%s: only containers have a layout and items", where)
+	}
+	if spec.Type == "image" && spec.Path == "" && spec.Icon == "" {
+		return fmt.Errorf("%s: an image needs a path or an icon", where)
+	}
+
+	var role FontRole
+	var color ColorRole
+	var align Align
+	if err = parseFontRole(spec.Font, &role); err != nil {
+		return fmt.Errorf("%s: %v", where, err)
+	}
+	if err = parseColorRole(spec.Color, &color); err != nil {
+		return fmt.Errorf("%s: %v", where, err)
+	}
+	if err = parsePanelAlign(spec.HAlign, &align); err != nil {
+		return fmt.Errorf("%s: %v", where, err)
+	}
+	if err = parsePanelAlign(spec.VAlign, &align); err != nil {
+		return fmt.Errorf("%s: %v", where, err)
+	}
+
+	if spec.Layout != nil {
+		if _, err = spec.Layout.build(); err != nil {
+			return fmt.Errorf("%s: %v", where, err)
+		}
+	}
+	if spec.Place != nil {
+		if _, err = spec.Place.build(); err != nil {
+			return fmt.Errorf("%s: %v", where, err)
+		}
+	}
+
+	if spec.Bind != nil {
+		switch {
+		case spec.Bind.Type == "command" && spec.Bind.Command == "":
+			return fmt.Errorf("%s: a command binding needs a command", where)
+		case spec.Bind.Type == "command" && (spec.Type == "label" || spec.Type == "list"):
+		case spec.Bind.Type == "desktop-entries" && spec.Type == "list":
+		default:
+			return fmt.Errorf("%s: a %s can not be bound to %q", where, spec.Type, spec.Bind.Type)
+		}
+	}
+
+	err = checkPanelActions(spec.Actions, where+".actions")
+	if err != nil {
+		return err
+	}
+	for _, action := range spec.Actions {
+		if action.Type == "launch" && (spec.Bind == nil || spec.Bind.Type != "desktop-entries") {
+			return fmt.Errorf("%s: launch only works in lists bound to desktop-entries", where)
+		}
+	}
+
+	for i := range spec.Items {
+		err = spec.Items[i].check(fmt.Sprintf("%s.items[%d]", where, i))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func checkPanelActions(actions []PanelActionSpec, where string) (err error) {
+	for i, action := range actions {
+		switch action.Type {
+		case "run", "sway":
+			if action.Command == "" {
+				return fmt.Errorf("%s[%d]: a %s action needs a command", where, i, action.Type)
+			}
+		case "close", "launch":
+		default:
+			return fmt.Errorf("%s[%d]: unknown action %q, expected run, sway, close or launch", where, i, action.Type)
+		}
+	}
+	return nil
+}
+
+// Gets the layout described by the spec
+func (spec *PanelLayoutSpec) build() (layout Layout, err error) {
+	padding, err := parsePanelInsets(spec.Padding)
+	if err != nil {
+		return nil, err
+	}
+
+	var align Align
+	err = parsePanelAlign(spec.Align, &align)
+	if err != nil {
+		return nil, err
+	}
+
+	switch spec.Type {
+	case "vbox":
+		return &VBox{padding: padding, spacing: spec.Spacing, align: align}, nil
+	case "hbox":
+		return &HBox{padding: padding, spacing: spec.Spacing, align: align}, nil
+	case "grid":
+		return &Grid{padding: padding, spacing: spec.Spacing, columns: spec.Columns, row_height: spec.RowHeight}, nil
+	case "stack":
+		return &Stack{padding: padding}, nil
+	}
+
+	return nil, fmt.Errorf("unknown layout %q, expected vbox, hbox, grid or stack", spec.Type)
+}
+
+// Gets the layout params described by the spec
+func (spec *PanelPlaceSpec) build() (params LayoutParams, err error) {
+	params = DEFAULT_LAYOUT_PARAMS
+	params.flex = spec.Flex
+
+	if params.margin, err = parsePanelInsets(spec.Margin); err != nil {
+		return params, err
+	}
+	if params.min, err = parsePanelVector(spec.Min); err != nil {
+		return params, err
+	}
+	if params.max, err = parsePanelVector(spec.Max); err != nil {
+		return params, err
+	}
+	if spec.HAlign != "" {
+		if err = parsePanelAlign(spec.HAlign, &params.halign); err != nil {
+			return params, err
+		}
+	}
+	if spec.VAlign != "" {
+		if err = parsePanelAlign(spec.VAlign, &params.valign); err != nil {
+			return params, err
+		}
+	}
+
+	return params, nil
+}
+
+// Parses insets like css: [all], [vertical, horizontal] or [top, right, bottom, left]
+func parsePanelInsets(values []int32) (insets Insets, err error) {
+	switch len(values) {
+	case 0:
+		return insets, nil
+	case 1:
+		return EvenInsets(values[0]), nil
+	case 2:
+		return Insets{values[0], values[1], values[0], values[1]}, nil
+	case 4:
+		return Insets{values[0], values[1], values[2], values[3]}, nil
+	}
+	return insets, fmt.Errorf("expected 1, 2 or 4 insets, got %d", len(values))
+}
+
+// Parses [x, y]
+func parsePanelVector(values []int32) (vec Vector, err error) {
+	switch len(values) {
+	case 0:
+		return vec, nil
+	case 2:
+		return Vector{values[0], values[1]}, nil
+	}
+	return vec, fmt.Errorf("expected [width, height], got %d values", len(values))
+}
+
+// Parses the name of an alignment (empty keeps align as it is)
+func parsePanelAlign(value string, align *Align) (err error) {
+	if value == "" {
+		return nil
+	}
+
+	parsed, ok := panel_aligns[strings.ToLower(value)]
+	if !ok {
+		return fmt.Errorf("unknown alignment %q, expected left, top, center, right, bottom or fill", value)
+	}
+
+	*align = parsed
+	return nil
+}
+
+// Parses the name of a color role (empty keeps role as it is)
+func parseColorRole(value string, role *ColorRole) (err error) {
+	if value == "" {
+		return nil
+	}
+
+	for i, name := range color_role_names {
+		if strings.EqualFold(name, value) {
+			*role = ColorRole(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown color %q, expected one of %s", value, strings.Join(color_role_names[:], ", "))
+}
+
+// Parses the name of a font role (empty keeps role as it is)
+func parseFontRole(value string, role *FontRole) (err error) {
+	if value == "" {
+		return nil
+	}
+
+	for i, name := range font_role_names {
+		if strings.EqualFold(name, value) {
+			*role = FontRole(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown font %q, expected one of %s", value, strings.Join(font_role_names[:], ", "))
+}
+
+/*
+##############################################################
+# Section: Building
+##############################################################
+*/
+
+// A row of a list in a panel
+type PanelRow struct {
+	text string
+	icon string
+
+	// the desktop file of desktop-entries rows
+	path string
+}
+
+// The rows of a list, they are its source
+type PanelList struct {
+	view *ListView
+	rows []PanelRow
+
+	actions []PanelActionSpec
+}
+
+func (list *PanelList) Len() int {
+	return len(list.rows)
+}
+
+// Keeps a binding up to date
+type PanelBinding struct {
+	spec PanelBindingSpec
+
+	// shows the output
+	apply func(output string)
+
+	// when the command runs next. Commands run in their own goroutine, which sends the output.
+	next    time.Time
+	running bool
+	done    bool
+	output  chan string
+}
+
+type PanelWindowHandler struct {
+	spec PanelSpec
+
+	cont *Container
+	exit *bool
+
+	// the items with actions and the lists
+	actions  map[Item][]PanelActionSpec
+	lists    map[Item]*PanelList
+	bindings []*PanelBinding
+
+	// the first list gets the keyboard
+	focus *PanelList
+
+	// the icons of the lists by name
+	icons map[string]*Picture
+}
+
+func (handler *PanelWindowHandler) Init(c *Container, e *bool) {
+	handler.cont = c
+	handler.exit = e
+	handler.actions = make(map[Item][]PanelActionSpec)
+	handler.lists = make(map[Item]*PanelList)
+	handler.icons = make(map[string]*Picture)
+
+	// the spec was checked when it was loaded
+	if handler.spec.Layout != nil {
+		layout, _ := handler.spec.Layout.build()
+		handler.cont.SetLayout(layout)
+	}
+
+	handler.addItems(handler.cont, handler.spec.Items)
+
+	if len(handler.spec.Actions) > 0 {
+		handler.actions[handler.cont] = handler.spec.Actions
+	}
+}
+
+// Builds the items of a container
+func (handler *PanelWindowHandler) addItems(cont *Container, specs []PanelItemSpec) {
+	for i, spec := range specs {
+		item, err := handler.buildItem(spec)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		params := DEFAULT_LAYOUT_PARAMS
+		if spec.Place != nil {
+			params, _ = spec.Place.build()
+		}
+
+		name := spec.Name
+		if name == "" {
+			name = spec.Type + "-" + strconv.Itoa(i)
+		}
+
+		cont.AddItemWithParams(name, item, params)
+
+		if len(spec.Actions) > 0 {
+			handler.actions[item] = spec.Actions
+		}
+	}
+}
+
+// Builds an item (and the items in it)
+func (handler *PanelWindowHandler) buildItem(spec PanelItemSpec) (item Item, err error) {
+	switch spec.Type {
+	case "container":
+		cont := &Container{}
+		if spec.Layout != nil {
+			layout, _ := spec.Layout.build()
+			cont.SetLayout(layout)
+		}
+		handler.addItems(cont, spec.Items)
+		return cont, nil
+
+	case "label":
+		label := &Label{
+			text:    spec.Text,
+			font:    HEADER_FONT,
+			valign:  CENTER,
+			halign:  LEFT,
+			color:   FOREGROUND,
+			bgcolor: BACKGROUND,
+		}
+		parseFontRole(spec.Font, &label.font)
+		parseColorRole(spec.Color, &label.color)
+		parsePanelAlign(spec.HAlign, &label.halign)
+		parsePanelAlign(spec.VAlign, &label.valign)
+
+		if spec.Bind != nil {
+			handler.bind(*spec.Bind, func(output string) {
+				label.SetText(strings.SplitN(strings.TrimSpace(output), "\n", 2)[0])
+			})
+		}
+		return label, nil
+
+	case "color":
+		unic := &Unicolor{color: SEPARATOR}
+		parseColorRole(spec.Color, &unic.color)
+		return unic, nil
+
+	case "image":
+		size := spec.Size
+		if size <= 0 {
+			size = fontHeight(HEADER_FONT)
+		}
+
+		var picture *Picture
+		if spec.Icon != "" {
+			picture, err = loadIconPicture(spec.Icon, size)
+		} else {
+			picture, err = loadPanelImage(expandHomePath(spec.Path), size)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &Texture{picture: picture}, nil
+
+	case "list":
+		return handler.buildList(spec), nil
+	}
+
+	return nil, fmt.Errorf("unknown item type %q", spec.Type)
+}
+
+// Builds a list and fills it from its binding
+func (handler *PanelWindowHandler) buildList(spec PanelItemSpec) (view *ListView) {
+	list := &PanelList{actions: spec.Actions}
+
+	row_height := spec.RowHeight
+	if row_height <= 0 {
+		row_height = fontHeight(HEADER_FONT) + 2*spacing()
+	}
+
+	view = &ListView{
+		row_height: row_height,
+		source:     list,
+		render: func(index int, size Vector, selected bool) (Item, error) {
+			return handler.getRowCont(list.rows[index], size, selected)
+		},
+		bgcolor:  BACKGROUND,
+		selcolor: ACCENT,
+		barcolor: MUTED,
+		on_activate: func(index int) {
+			handler.runActions(list.actions, &list.rows[index])
+		},
+	}
+	list.view = view
+
+	handler.lists[view] = list
+	if handler.focus == nil {
+		handler.focus = list
+	}
+
+	if spec.Bind == nil {
+		return view
+	}
+
+	switch spec.Bind.Type {
+	case "desktop-entries":
+		entries := loadSearchEntries(desktop_file_paths, getFrecencyScores())
+		for _, result := range Search(entries, "", 0) {
+			list.rows = append(list.rows, PanelRow{
+				text: result.Entry.Name,
+				icon: result.Entry.Entry.Icon(),
+				path: result.Entry.Path,
+			})
+		}
+		view.Refresh()
+
+	case "command":
+		handler.bind(*spec.Bind, func(output string) {
+			list.rows = nil
+			for _, line := range strings.Split(output, "\n") {
+				if strings.TrimSpace(line) != "" {
+					list.rows = append(list.rows, PanelRow{text: line})
+				}
+			}
+			view.Refresh()
+		})
+	}
+
+	return view
+}
+
+// Gets a container showing a row of a list
+func (handler *PanelWindowHandler) getRowCont(row PanelRow, size Vector, selected bool) (cont *Container, err error) {
+	// leaving room for the selection marker
+	cont = &Container{size: size, layout: &HBox{padding: Insets{0, spacing(), 0, MARKER_WIDTH + spacing()}, spacing: spacing()}}
+
+	if row.icon != "" {
+		icon, ok := handler.icons[row.icon]
+		if !ok {
+			icon, err = loadIconPicture(row.icon, fontHeight(HEADER_FONT))
+			if err != nil {
+				// without an icon, the text still lines up with the others
+				icon, err = NewFilledPicture(Vector{fontHeight(HEADER_FONT), fontHeight(HEADER_FONT)}, BACKGROUND.RGB()), nil
+			}
+			handler.icons[row.icon] = icon
+		}
+
+		cont.AddItemWithParams("icon", &Texture{picture: icon}, LayoutParams{halign: LEFT, valign: CENTER})
+	}
+
+	color := MUTED
+	if selected {
+		color = FOREGROUND
+	}
+
+	cont.AddItemWithParams("text", &Label{
+		text:    row.text,
+		font:    HEADER_FONT,
+		valign:  CENTER,
+		halign:  LEFT,
+		color:   color,
+		bgcolor: BACKGROUND,
+	}, LayoutParams{flex: 1, halign: FILL, valign: FILL})
+
+	return cont, nil
+}
+
+// Loads an image file at the given height
+func loadPanelImage(path string, height int32) (picture *Picture, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	bounds := img.Bounds()
+	if bounds.Dy() == int(height) || bounds.Dy() == 0 {
+		return NewPicture(img), nil
+	}
+
+	// keep the aspect ratio
+	return NewPicture(scaleImage(img, Vector{int32(bounds.Dx()) * height / int32(bounds.Dy()), height})), nil
+}
+
+/*
+##############################################################
+# Section: Bindings & Actions
+##############################################################
+*/
+
+// Keeps something up to date with a binding
+func (handler *PanelWindowHandler) bind(spec PanelBindingSpec, apply func(output string)) {
+	handler.bindings = append(handler.bindings, &PanelBinding{
+		spec:   spec,
+		apply:  apply,
+		output: make(chan string, 1),
+	})
+}
+
+// Runs the commands of the bindings that are due, and shows the output of the finished ones
+func (handler *PanelWindowHandler) Update() {
+	now := time.Now()
+
+	for _, binding := range handler.bindings {
+		select {
+		case output := <-binding.output:
+			binding.apply(output)
+			binding.running = false
+		default:
+		}
+
+		if binding.running || binding.done || now.Before(binding.next) {
+			continue
+		}
+
+		if binding.spec.Interval > 0 {
+			binding.next = now.Add(time.Duration(binding.spec.Interval * float64(time.Second)))
+		} else {
+			binding.done = true
+		}
+		binding.running = true
+
+		// slow commands must not block the window
+		go func(binding *PanelBinding) {
+			output, err := exec.Command("sh", "-c", binding.spec.Command).Output()
+			if err != nil {
+				fmt.Println(binding.spec.Command+":", err)
+			}
+
+			binding.output <- string(output)
+			WakeWindow()
+		}(binding)
+	}
+}
+
+// Checks if a command of a binding is still running (rendering waits for them)
+func (handler *PanelWindowHandler) Busy() bool {
+	for _, binding := range handler.bindings {
+		if binding.running {
+			return true
+		}
+	}
+	return false
+}
+
+// Runs actions, for a row of a list if row is not nil
+func (handler *PanelWindowHandler) runActions(actions []PanelActionSpec, row *PanelRow) {
+	for _, action := range actions {
+		var err error
+
+		switch action.Type {
+		case "run":
+			command := action.Command
+			if row != nil {
+				command = strings.Replace(command, "{text}", shellJoin([]string{row.text}), -1)
+			}
+			err = startDetached([]string{"sh", "-c", command}, "")
+		case "sway":
+			command := action.Command
+			if row != nil {
+				command = strings.Replace(command, "{text}", swayQuote(row.text), -1)
+			}
+			err = RunSwayCommand(command)
+		case "launch":
+			if row == nil || row.path == "" {
+				continue
+			}
+			err = launchDesktopFile(row.path)
+			if err == nil {
+				err = recordLaunch(row.path)
+			}
+		case "close":
+			*handler.exit = false
+		}
+
+		// the actions after a failed one are not run
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+}
+
+func (handler *PanelWindowHandler) HandleEvent(event sdl.Event) {
+	switch ty := event.(type) {
+	case *sdl.TextInputEvent:
+		if actions, ok := handler.spec.Keys[GetInputText(ty)]; ok {
+			handler.runActions(actions, nil)
+			return
+		}
+	case *sdl.MouseButtonEvent:
+		if ty.Type == sdl.MOUSEBUTTONDOWN && ty.Button == sdl.BUTTON_LEFT {
+			handler.click(handler.cont.FromWindow(Vector{ty.X, ty.Y}))
+		}
+		return
+	}
+
+	if handler.focus != nil {
+		handler.focus.view.HandleEvent(event)
+	}
+}
+
+// Runs the actions of the innermost item with actions at a point of the main container
+func (handler *PanelWindowHandler) click(point Vector) {
+	var target Item
+	var target_local Vector
+
+	if _, ok := handler.actions[handler.cont]; ok {
+		target, target_local = handler.cont, point
+	}
+
+	// go into the containers as far as possible
+	for cont := handler.cont; cont != nil; {
+		_, item, local := cont.ChildAt(point)
+		if item == nil {
+			break
+		}
+
+		if _, ok := handler.actions[item]; ok {
+			target, target_local = item, local
+		}
+		if _, ok := handler.lists[item]; ok {
+			target, target_local = item, local
+		}
+
+		cont, _ = item.(*Container)
+		point = local
+	}
+
+	if target == nil {
+		return
+	}
+
+	// a click on a list selects a row and runs the actions for it
+	if list, ok := handler.lists[target]; ok {
+		index := list.view.RowAt(target_local)
+		if index < 0 {
+			return
+		}
+
+		handler.focus = list
+		list.view.Select(index)
+		handler.runActions(list.actions, &list.rows[index])
+		return
+	}
+
+	handler.runActions(handler.actions[target], nil)
+}
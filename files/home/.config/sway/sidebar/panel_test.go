@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Writes a panel file and loads it
+func loadTestPanel(t *testing.T, content string) (spec PanelSpec, err error) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.json")
+	err = os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return loadPanelSpec(path)
+}
+
+func TestLoadPanelSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string // a part of the error, empty if the panel is valid
+	}{
+		{"empty panel", `{}`, ""},
+		{"labels", `{"layout": {"type": "vbox"}, "items": [{"type": "label", "text": "hi", "font": "title", "color": "Muted", "halign": "center"}]}`, ""},
+		{"not json", `{"items": [`, "unexpected EOF"},
+		{"unknown field", `{"itmes": []}`, `unknown field "itmes"`},
+		{"unknown field of an item", `{"items": [{"type": "label", "txt": "hi"}]}`, `unknown field "txt"`},
+		{"bad type of a field", `{"items": [{"type": "label", "text": 3}]}`, "cannot unmarshal number"},
+		{"bad size", `{"items": [{"type": "image", "icon": "foot", "size": "big"}]}`, "cannot unmarshal string"},
+		{"unknown item type", `{"items": [{"type": "button"}]}`, `panel.items[0]: unknown type "button"`},
+		{"root is not a container", `{"type": "label"}`, "the panel is a container"},
+		{"items of a label", `{"items": [{"type": "label", "items": [{"type": "label"}]}]}`, "only containers have a layout and items"},
+		{"image without a file", `{"items": [{"type": "image"}]}`, "needs a path or an icon"},
+		{"unknown font", `{"items": [{"type": "label", "font": "Huge"}]}`, `unknown font "Huge"`},
+		{"unknown color", `{"items": [{"type": "color", "color": "pink"}]}`, `unknown color "pink"`},
+		{"unknown alignment", `{"items": [{"type": "label", "valign": "middle"}]}`, `unknown alignment "middle"`},
+		{"unknown layout", `{"layout": {"type": "flow"}}`, `unknown layout "flow"`},
+		{"path of a nested item", `{"items": [{"type": "container", "name": "row", "items": [{"type": "list", "bind": {"type": "nothing"}}]}]}`, `panel.items[0] (row).items[0]: a list can not be bound to "nothing"`},
+
+		// bindings by item type
+		{"label bound to a command", `{"items": [{"type": "label", "bind": {"type": "command", "command": "date", "interval": 1}}]}`, ""},
+		{"list bound to a command", `{"items": [{"type": "list", "bind": {"type": "command", "command": "ls"}}]}`, ""},
+		{"list bound to desktop entries", `{"items": [{"type": "list", "bind": {"type": "desktop-entries"}}]}`, ""},
+		{"label bound to desktop entries", `{"items": [{"type": "label", "bind": {"type": "desktop-entries"}}]}`, `a label can not be bound to "desktop-entries"`},
+		{"color bound to a command", `{"items": [{"type": "color", "bind": {"type": "command", "command": "date"}}]}`, `a color can not be bound to "command"`},
+		{"command binding without a command", `{"items": [{"type": "label", "bind": {"type": "command"}}]}`, "needs a command"},
+
+		// actions
+		{"actions", `{"items": [{"type": "label", "actions": [{"type": "run", "command": "foot"}, {"type": "sway", "command": "reload"}, {"type": "close"}]}]}`, ""},
+		{"unknown action", `{"items": [{"type": "label", "actions": [{"type": "open"}]}]}`, `panel.items[0].actions[0]: unknown action "open"`},
+		{"run without a command", `{"items": [{"type": "label", "actions": [{"type": "close"}, {"type": "run"}]}]}`, "panel.items[0].actions[1]: a run action needs a command"},
+		{"launch in desktop entries", `{"items": [{"type": "list", "bind": {"type": "desktop-entries"}, "actions": [{"type": "launch"}]}]}`, ""},
+		{"launch in a command list", `{"items": [{"type": "list", "bind": {"type": "command", "command": "ls"}, "actions": [{"type": "launch"}]}]}`, "launch only works in lists bound to desktop-entries"},
+		{"launch in a label", `{"items": [{"type": "label", "actions": [{"type": "launch"}]}]}`, "launch only works in lists bound to desktop-entries"},
+		{"keys", `{"keys": {"l": [{"type": "run", "command": "swaylock"}]}}`, ""},
+		{"bad key action", `{"keys": {"l": [{"type": "sway"}]}}`, "keys.l[0]: a sway action needs a command"},
+
+		// insets and vectors
+		{"one inset", `{"layout": {"type": "vbox", "padding": [8]}}`, ""},
+		{"two insets", `{"layout": {"type": "vbox", "padding": [8, 4]}}`, ""},
+		{"three insets", `{"layout": {"type": "vbox", "padding": [8, 4, 2]}}`, "expected 1, 2 or 4 insets, got 3"},
+		{"four insets", `{"items": [{"type": "label", "place": {"margin": [1, 2, 3, 4]}}]}`, ""},
+		{"five insets", `{"items": [{"type": "label", "place": {"margin": [1, 2, 3, 4, 5]}}]}`, "expected 1, 2 or 4 insets, got 5"},
+		{"size", `{"items": [{"type": "label", "place": {"min": [10, 20], "max": [30, 40]}}]}`, ""},
+		{"size with one value", `{"items": [{"type": "label", "place": {"min": [10]}}]}`, "expected [width, height], got 1 values"},
+		{"size with three values", `{"items": [{"type": "label", "place": {"max": [1, 2, 3]}}]}`, "expected [width, height], got 3 values"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := loadTestPanel(t, test.content)
+
+			switch {
+			case test.err == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case test.err != "" && err == nil:
+				t.Errorf("no error, want one containing %q", test.err)
+			case test.err != "" && !strings.Contains(err.Error(), test.err):
+				t.Errorf("error %q does not contain %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestPanelInsets(t *testing.T) {
+	tests := []struct {
+		values []int32
+		want   Insets
+	}{
+		{nil, Insets{}},
+		{[]int32{8}, Insets{8, 8, 8, 8}},
+		{[]int32{8, 4}, Insets{8, 4, 8, 4}},
+		{[]int32{1, 2, 3, 4}, Insets{1, 2, 3, 4}},
+	}
+
+	for _, test := range tests {
+		got, err := parsePanelInsets(test.values)
+		if err != nil || got != test.want {
+			t.Errorf("parsePanelInsets(%v) = %v, %v, want %v", test.values, got, err, test.want)
+		}
+	}
+}
+
+// The example shipped with the sidebar has to stay valid
+func TestExamplePanel(t *testing.T) {
+	spec, err := loadPanelSpec(filepath.Join(PANELS_DIR, "tools"+PANEL_EXTENSION))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(spec.Items) == 0 {
+		t.Error("the example panel has no items")
+	}
+}
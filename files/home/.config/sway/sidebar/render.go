@@ -0,0 +1,312 @@
+package main
+
+/*
+##############################################################
+# Section: Imports
+##############################################################
+*/
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/*
+######################################################################################################
+######################################################################################################
+## Chapter: Rendering																				##
+######################################################################################################
+######################################################################################################
+*/
+
+/*
+##############################################################
+# Section: Constants & Fields
+##############################################################
+*/
+
+// `sidebar render --out file.png <window>` draws a window into a PNG file, without a display.
+// It uses the dummy video driver of sdl, so it works without a wayland (or X) session.
+
+// The display size windows are rendered for (they take 1/ScreenFraction of its width)
+var RENDER_DISPLAY_SIZE = Vector{1920, 1080}
+
+// How long rendering waits for handlers that are still loading something (see Busy)
+const RENDER_TIMEOUT = 5 * time.Second
+
+// Colors of pixels in golden images may be off by this much (per channel, 0-255),
+// e.g. because of different font antialiasing
+const GOLDEN_CHANNEL_TOLERANCE = 2
+
+// Handlers that load something in the background (like the commands of panels).
+// Rendering waits until they are done, so the image shows the result.
+type Busy interface {
+	Busy() bool
+}
+
+/*
+##############################################################
+# Section: Rendering
+##############################################################
+*/
+
+// Runs the render command: draws a window into a PNG file, optionally comparing it with a golden image
+func runRender(args []string, theme string) (err error) {
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	out := flags.String("out", "sidebar.png", "the PNG file to write")
+	display := flags.String("display", formatSize(RENDER_DISPLAY_SIZE), "the display size (WxH) the window is rendered for")
+	size := flags.String("size", "", "the size of the image (WxH), instead of the size of the window")
+	golden := flags.String("golden", "", "a PNG file the image has to match")
+	tolerance := flags.Float64("tolerance", 0, "the fraction of pixels that may differ from the golden image")
+	update := flags.Bool("update", false, "write the image to the golden file instead of comparing them")
+	use_sway := flags.Bool("sway", false, "show the state of the running sway (without, renders are reproducible)")
+	use_image := flags.Bool("image", false, "draw with the pure Go canvas instead of sdl")
+
+	err = flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("usage: sidebar render [--out file.png] [--golden file.png] <window> [arguments]")
+	}
+
+	// no display (or sound card) needed
+	os.Setenv("SDL_VIDEODRIVER", "dummy")
+	os.Setenv("SDL_AUDIODRIVER", "dummy")
+	Initialize()
+	defer sdl.Quit()
+	defer closeFonts()
+
+	if !*use_sway && sway_ipc != nil {
+		sway_ipc.Close()
+		sway_ipc = nil
+	}
+
+	err = parseSize(*display, &display_size)
+	if err != nil {
+		return err
+	}
+
+	handler, window, err := getWindowHandler(flags.Arg(0), flags.Args())
+	if err != nil {
+		return err
+	}
+
+	// invalid settings keep their defaults, like when the window is opened
+	err = loadConfig(window, theme)
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	image_size := Vector{display_size.x / int32(SCREEN_FRACTION), display_size.y}
+	if *size != "" {
+		err = parseSize(*size, &image_size)
+		if err != nil {
+			return err
+		}
+	}
+
+	img, err := renderWindow(handler, image_size, *use_image)
+	if err != nil {
+		return err
+	}
+
+	err = writePNG(*out, img)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case *golden != "" && *update:
+		return writePNG(*golden, img)
+	case *golden != "":
+		return compareGolden(img, *golden, *tolerance)
+	}
+
+	return nil
+}
+
+// Draws a window into an image, the same way CreateWindow draws it onto the screen.
+// With use_image, it is drawn by an ImageCanvas instead of onto an sdl surface.
+func renderWindow(handler WindowHandler, size Vector, use_image bool) (img *image.RGBA, err error) {
+	// the window is not closed by anything here
+	running := true
+
+	cont := Container{position: Vector{0, 0}, size: size}
+
+	// the text has to be measured by what draws it
+	if use_image {
+		useImageFonts()
+	}
+
+	background_color = BACKGROUND.RGB()
+
+	handler.Init(&cont, &running)
+	handler.Update()
+
+	// wait for what is loaded in the background
+	if busy, ok := handler.(Busy); ok {
+		start := time.Now()
+		for busy.Busy() && time.Since(start) < RENDER_TIMEOUT {
+			time.Sleep(10 * time.Millisecond)
+			handler.Update()
+		}
+	}
+
+	if use_image {
+		img = image.NewRGBA(image.Rect(0, 0, int(size.x), int(size.y)))
+		canvas := NewImageCanvas(img)
+		canvas.FillRect(Vector{0, 0}, size, background_color)
+		err = cont.Draw(canvas)
+		if err != nil {
+			return nil, err
+		}
+		return img, nil
+	}
+
+	surface, err := sdl.CreateRGBSurfaceWithFormat(0, size.x, size.y, 32, uint32(sdl.PIXELFORMAT_RGB888))
+	if err != nil {
+		return nil, err
+	}
+	defer surface.Free()
+
+	canvas := NewSurfaceCanvas(surface)
+	canvas.FillRect(Vector{0, 0}, size, background_color)
+	err = cont.Draw(canvas)
+	if err != nil {
+		return nil, err
+	}
+
+	return surfaceToImage(surface)
+}
+
+// Copies the pixels of a surface into an image
+func surfaceToImage(surface *sdl.Surface) (img *image.RGBA, err error) {
+	// RGBA32 has the same byte order as image.RGBA
+	converted, err := surface.ConvertFormat(uint32(sdl.PIXELFORMAT_RGBA32), 0)
+	if err != nil {
+		return nil, err
+	}
+	defer converted.Free()
+
+	img = image.NewRGBA(image.Rect(0, 0, int(converted.W), int(converted.H)))
+
+	pixels := converted.Pixels()
+	for y := 0; y < int(converted.H); y++ {
+		row := pixels[y*int(converted.Pitch):]
+		copy(img.Pix[y*img.Stride:(y+1)*img.Stride], row[:img.Stride])
+	}
+
+	return img, nil
+}
+
+// Writes an image into a PNG file
+func writePNG(path string, img image.Image) (err error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = png.Encode(file, img)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Parses a size like 1920x1080
+func parseSize(value string, size *Vector) (err error) {
+	var width, height int32
+	_, err = fmt.Sscanf(value, "%dx%d", &width, &height)
+	if err != nil || width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid size %q, expected WIDTHxHEIGHT", value)
+	}
+
+	*size = Vector{width, height}
+	return nil
+}
+
+// The reverse of parseSize
+func formatSize(size Vector) string {
+	return fmt.Sprintf("%dx%d", size.x, size.y)
+}
+
+/*
+##############################################################
+# Section: Golden images
+##############################################################
+*/
+
+// Compares an image with a golden image (a PNG file of how it should look).
+// tolerance is the fraction of pixels that may differ by more than GOLDEN_CHANNEL_TOLERANCE.
+// If there are more, the differing pixels are written into <golden>.diff.png, shown in red.
+func compareGolden(img image.Image, path string, tolerance float64) (err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	golden, err := png.Decode(file)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	bounds := img.Bounds()
+	golden_bounds := golden.Bounds()
+	if bounds.Dx() != golden_bounds.Dx() || bounds.Dy() != golden_bounds.Dy() {
+		return fmt.Errorf("the image is %dx%d, but %s is %dx%d", bounds.Dx(), bounds.Dy(), path, golden_bounds.Dx(), golden_bounds.Dy())
+	}
+
+	// the matching pixels are shown faded, so the differences stand out
+	diff := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	differing := 0
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			got := color.RGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.RGBA)
+			want := color.RGBAModel.Convert(golden.At(golden_bounds.Min.X+x, golden_bounds.Min.Y+y)).(color.RGBA)
+
+			if colorsMatch(got, want) {
+				gray := uint8((uint32(want.R) + uint32(want.G) + uint32(want.B)) / 3 / 4)
+				diff.SetRGBA(x, y, color.RGBA{gray, gray, gray, 0xff})
+				continue
+			}
+
+			differing++
+			diff.SetRGBA(x, y, color.RGBA{0xff, 0, 0, 0xff})
+		}
+	}
+
+	total := bounds.Dx() * bounds.Dy()
+	if total == 0 || float64(differing)/float64(total) <= tolerance {
+		return nil
+	}
+
+	diff_path := strings.TrimSuffix(path, ".png") + ".diff.png"
+	err = writePNG(diff_path, diff)
+	if err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%d of %d pixels (%.2f%%) differ from %s, see %s", differing, total, 100*float64(differing)/float64(total), path, diff_path)
+}
+
+// Checks if two colors are the same, within GOLDEN_CHANNEL_TOLERANCE
+func colorsMatch(a color.RGBA, b color.RGBA) bool {
+	channel := func(a uint8, b uint8) bool {
+		difference := int(a) - int(b)
+		return difference <= GOLDEN_CHANNEL_TOLERANCE && difference >= -GOLDEN_CHANNEL_TOLERANCE
+	}
+	return channel(a.R, b.R) && channel(a.G, b.G) && channel(a.B, b.B) && channel(a.A, b.A)
+}
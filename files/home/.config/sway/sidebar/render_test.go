@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// `go test -update` writes the renders into the golden files instead of comparing them
+var update_goldens = flag.Bool("update", false, "write the renders into the golden files in testdata")
+
+// The fraction of pixels of a render that may differ from its golden image
+const GOLDEN_TOLERANCE = 0.001
+
+// Checks that an image matches a golden image (see compareGolden).
+// With -update, the image replaces the golden image instead.
+func assertGolden(t *testing.T, img *image.RGBA, path string, tolerance float64) {
+	t.Helper()
+
+	if *update_goldens {
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err == nil {
+			err = writePNG(path, img)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		t.Fatalf("%s does not exist, run the test with -update to create it", path)
+	}
+
+	err := compareGolden(img, path, tolerance)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// Renders windows the same way on every machine: no sway, no config or history of the user,
+// the desktop files in testdata and no icons (the results show empty space instead)
+func useRenderEnvironment(t *testing.T) {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, variable := range []string{"XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_CACHE_HOME", "XDG_STATE_HOME", "SWAYSOCK", "I3SOCK"} {
+		t.Setenv(variable, "")
+	}
+	t.Setenv("XDG_DATA_DIRS", home)
+	t.Setenv("LC_ALL", "C")
+
+	applications, err := filepath.Abs(filepath.Join("testdata", "applications"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old_paths, old_display, old_ipc, old_resolver := desktop_file_paths, display_size, sway_ipc, icon_resolver
+	desktop_file_paths = []string{applications}
+	display_size = Vector{960, 540}
+	sway_ipc = nil
+	icon_resolver = nil
+
+	t.Cleanup(func() {
+		desktop_file_paths, display_size, sway_ipc, icon_resolver = old_paths, old_display, old_ipc, old_resolver
+	})
+
+	useTestTheme(t)
+}
+
+func TestRenderGolden(t *testing.T) {
+	for _, window := range []string{"power", "run", "desktop"} {
+		t.Run(window, func(t *testing.T) {
+			useRenderEnvironment(t)
+
+			handler, _, err := getWindowHandler(window, []string{window})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			size := Vector{display_size.x / int32(SCREEN_FRACTION), display_size.y}
+			img, err := renderWindow(handler, size, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertGolden(t, img, filepath.Join("testdata", window+".png"), GOLDEN_TOLERANCE)
+		})
+	}
+}
+
+func TestCompareGolden(t *testing.T) {
+	dir := t.TempDir()
+	golden := filepath.Join(dir, "golden.png")
+
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	NewImageCanvas(img).FillRect(Vector{0, 0}, Vector{10, 10}, 0x102030)
+	if err := writePNG(golden, img); err != nil {
+		t.Fatal(err)
+	}
+
+	// small differences of the colors are fine
+	NewImageCanvas(img).FillRect(Vector{0, 0}, Vector{1, 1}, 0x112131)
+	if err := compareGolden(img, golden, 0); err != nil {
+		t.Errorf("slightly different colors do not match: %v", err)
+	}
+
+	// other pixels only up to the tolerance
+	NewImageCanvas(img).FillRect(Vector{0, 0}, Vector{1, 1}, 0xffffff)
+	if err := compareGolden(img, golden, 0.01); err != nil {
+		t.Errorf("1%% of the pixels differ, with a tolerance of 1%%: %v", err)
+	}
+	if err := compareGolden(img, golden, 0); err == nil {
+		t.Error("a different pixel matches without tolerance")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "golden.diff.png")); err != nil {
+		t.Errorf("no diff image: %v", err)
+	}
+
+	// and the sizes have to match
+	if err := compareGolden(image.NewRGBA(image.Rect(0, 0, 5, 10)), golden, 1); err == nil {
+		t.Error("images of different sizes match")
+	}
+}
@@ -0,0 +1,393 @@
+package main
+
+/*
+##############################################################
+# Section: Imports
+##############################################################
+*/
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+/*
+######################################################################################################
+######################################################################################################
+## Chapter: Search																					##
+######################################################################################################
+######################################################################################################
+*/
+
+/*
+##############################################################
+# Section: Constants & Fields
+##############################################################
+*/
+
+// How much the fields of an entry count when they match
+const (
+	SEARCH_WEIGHT_NAME        = 1.0
+	SEARCH_WEIGHT_BINARY      = 0.9
+	SEARCH_WEIGHT_GENERICNAME = 0.8
+	SEARCH_WEIGHT_KEYWORDS    = 0.7
+)
+
+// How much often and recently used entries are preferred over better matching ones
+const SEARCH_USAGE_WEIGHT = 10.0
+
+// scores of the fuzzy matcher
+const (
+	FUZZY_MATCH       = 16
+	FUZZY_CONSECUTIVE = 24
+	FUZZY_WORD_START  = 20
+	FUZZY_TEXT_START  = 16
+	FUZZY_GAP         = -1
+	FUZZY_MAX_GAP     = -12
+	FUZZY_TYPO        = -24
+)
+
+/*
+##############################################################
+# Section: Types
+##############################################################
+*/
+
+// A desktop entry prepared for searching
+type SearchEntry struct {
+	Path  string
+	Entry *DesktopEntry
+
+	Name        string
+	GenericName string
+	Keywords    []string
+	Binary      string
+
+	// how often and recently the entry was launched (see LaunchHistory.Frecency)
+	Frecency float64
+}
+
+type SearchResult struct {
+	Entry *SearchEntry
+	Score float64
+
+	// the rune indices of the matched characters in Name (nil if Name did not match)
+	Highlights []int
+}
+
+/*
+##############################################################
+# Section: Entries
+##############################################################
+*/
+
+// Loads all desktop entries that can be launched.
+// If an entry with the same desktop file ID is found in multiple directories,
+// the one in the first directory wins.
+func loadSearchEntries(dirs []string, frecency map[string]float64) (entries []*SearchEntry) {
+	seen := make(map[string]bool)
+
+	for _, dir := range dirs {
+		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if info == nil || info.IsDir() || !strings.HasSuffix(path, ".desktop") {
+				return nil
+			}
+
+			// the desktop file ID (subdirectories are separated by -)
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return nil
+			}
+			id := strings.Replace(rel, string(filepath.Separator), "-", -1)
+			if seen[id] {
+				return nil
+			}
+			seen[id] = true
+
+			entry, err := parseDesktopFile(path)
+			if err != nil {
+				if _, ok := err.(DesktopEntryErrors); !ok || entry.Main() == nil {
+					return nil
+				}
+			}
+
+			// hidden entries also hide the ones with the same ID, which is why seen is set before this
+			if entry.Type() != "Application" || entry.NoDisplay() || entry.Hidden() {
+				return nil
+			}
+
+			entries = append(entries, newSearchEntry(path, entry, frecency[path]))
+			return nil
+		})
+	}
+
+	return entries
+}
+
+func newSearchEntry(path string, entry *DesktopEntry, frecency float64) (search_entry *SearchEntry) {
+	search_entry = &SearchEntry{
+		Path:        path,
+		Entry:       entry,
+		Name:        entry.Name(),
+		GenericName: entry.GenericName(),
+		Keywords:    entry.Keywords(),
+		Frecency:    frecency,
+	}
+
+	if argv, err := splitExec(entry.Exec()); err == nil {
+		search_entry.Binary = filepath.Base(argv[0])
+	}
+
+	return search_entry
+}
+
+/*
+##############################################################
+# Section: Searching
+##############################################################
+*/
+
+// Finds the entries matching query, best first.
+// An empty query returns all entries, most used first.
+func Search(entries []*SearchEntry, query string, limit int) (results []SearchResult) {
+	query = strings.TrimSpace(query)
+
+	for _, entry := range entries {
+		result := SearchResult{Entry: entry}
+
+		if query != "" {
+			score, highlights, ok := entry.match(query)
+			if !ok {
+				continue
+			}
+			result.Score = score
+			result.Highlights = highlights
+		}
+
+		result.Score += SEARCH_USAGE_WEIGHT * math.Log1p(entry.Frecency)
+		results = append(results, result)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return strings.ToLower(results[i].Entry.Name) < strings.ToLower(results[j].Entry.Name)
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results
+}
+
+// Matches the query against all searchable fields and returns the best weighted score
+func (entry *SearchEntry) match(query string) (score float64, highlights []int, ok bool) {
+	try := func(text string, weight float64, highlight bool) {
+		fscore, positions, matched := fuzzyMatch(query, text)
+		if !matched || float64(fscore)*weight <= score && ok {
+			return
+		}
+
+		score, ok = float64(fscore)*weight, true
+		if highlight {
+			highlights = positions
+		} else {
+			highlights = nil
+		}
+	}
+
+	try(entry.Name, SEARCH_WEIGHT_NAME, true)
+	try(entry.Binary, SEARCH_WEIGHT_BINARY, false)
+	try(entry.GenericName, SEARCH_WEIGHT_GENERICNAME, false)
+	for _, keyword := range entry.Keywords {
+		try(keyword, SEARCH_WEIGHT_KEYWORDS, false)
+	}
+
+	return score, highlights, ok
+}
+
+/*
+##############################################################
+# Section: Fuzzy matching
+##############################################################
+*/
+
+// Matches pattern against text (ignoring case) and returns the rune positions of the matched characters.
+// The characters of pattern have to appear in text in order, but not necessarily next to each other.
+// If they do not, small typos are tolerated when comparing pattern with the beginnings of words.
+func fuzzyMatch(pattern string, text string) (score int, positions []int, ok bool) {
+	p := []rune(strings.ToLower(pattern))
+	t := []rune(text)
+	if len(p) == 0 || len(t) == 0 {
+		return 0, nil, false
+	}
+
+	// lower case rune by rune, so the positions stay the same
+	lower := make([]rune, len(t))
+	for i, r := range t {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// try every possible start of the match, keep the best one
+	best := math.MinInt32
+	for start := range lower {
+		if lower[start] != p[0] {
+			continue
+		}
+
+		s, pos, matched := fuzzyMatchFrom(p, t, lower, start)
+		if matched && s > best {
+			best, positions = s, pos
+		}
+	}
+
+	if positions != nil {
+		return best, positions, true
+	}
+
+	return typoMatch(p, t, lower)
+}
+
+// Greedily matches p starting at text position start
+func fuzzyMatchFrom(p []rune, t []rune, lower []rune, start int) (score int, positions []int, ok bool) {
+	pi := 0
+	last := -1
+
+	for ti := start; ti < len(lower) && pi < len(p); ti++ {
+		if lower[ti] != p[pi] {
+			continue
+		}
+
+		score += FUZZY_MATCH
+
+		if last >= 0 && ti == last+1 {
+			score += FUZZY_CONSECUTIVE
+		} else if last >= 0 {
+			gap := FUZZY_GAP * (ti - last - 1)
+			if gap < FUZZY_MAX_GAP {
+				gap = FUZZY_MAX_GAP
+			}
+			score += gap
+		}
+
+		if ti == 0 {
+			score += FUZZY_TEXT_START
+		}
+		if isWordStart(t, ti) {
+			score += FUZZY_WORD_START
+		}
+
+		positions = append(positions, ti)
+		last = ti
+		pi++
+	}
+
+	return score, positions, pi == len(p)
+}
+
+// Compares p with the beginning of every word of the text, allowing a few typos
+func typoMatch(p []rune, t []rune, lower []rune) (score int, positions []int, ok bool) {
+	// short patterns would match almost anything
+	allowed := 0
+	switch {
+	case len(p) >= 8:
+		allowed = 2
+	case len(p) >= 4:
+		allowed = 1
+	}
+	if allowed == 0 {
+		return 0, nil, false
+	}
+
+	best_distance := allowed + 1
+	best_start := -1
+	best_length := 0
+	for start := range lower {
+		if !isWordStart(t, start) {
+			continue
+		}
+
+		// the word may also be a little shorter or longer than the pattern
+		for length := len(p) - allowed; length <= len(p)+allowed; length++ {
+			if length <= 0 || start+length > len(lower) {
+				continue
+			}
+
+			distance := editDistance(p, lower[start:start+length])
+			if distance < best_distance {
+				best_distance, best_start, best_length = distance, start, length
+			}
+		}
+	}
+
+	if best_start < 0 {
+		return 0, nil, false
+	}
+
+	for i := best_start; i < best_start+best_length; i++ {
+		positions = append(positions, i)
+	}
+
+	score = FUZZY_MATCH*len(p) + FUZZY_TYPO*best_distance
+	if best_start == 0 {
+		score += FUZZY_TEXT_START
+	}
+
+	return score, positions, true
+}
+
+// Checks if a word begins at index i (after a separator or a lower case letter followed by an upper case one)
+func isWordStart(t []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+
+	prev, cur := t[i-1], t[i]
+	if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+		return unicode.IsLetter(cur) || unicode.IsDigit(cur)
+	}
+
+	return unicode.IsLower(prev) && unicode.IsUpper(cur)
+}
+
+// Damerau-Levenshtein distance (with adjacent transpositions) between a and b
+func editDistance(a []rune, b []rune) (distance int) {
+	rows := make([][]int, len(a)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			rows[i][j] = minInt(rows[i-1][j]+1, minInt(rows[i][j-1]+1, rows[i-1][j-1]+cost))
+
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				rows[i][j] = minInt(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return rows[len(a)][len(b)]
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		text      string
+		ok        bool
+		positions []int
+	}{
+		{"fire", "Firefox", true, []int{0, 1, 2, 3}},
+		{"FIRE", "firefox", true, []int{0, 1, 2, 3}},
+		{"ff", "Firefox", true, []int{0, 4}},
+		{"lo", "LibreOffice Calc", true, []int{0, 5}},
+
+		// the best start wins, not the first one
+		{"calc", "LibreOffice Calc", true, []int{12, 13, 14, 15}},
+		{"ic", "LibreOffice Calc", true, []int{8, 9}},
+
+		// positions are runes, not bytes
+		{"ka", "Über Karten", true, []int{5, 6}},
+
+		{"", "Firefox", false, nil},
+		{"fire", "", false, nil},
+		{"xyz", "Firefox", false, nil},
+		{"fxf", "Firefox", false, nil},
+	}
+
+	for _, test := range tests {
+		_, positions, ok := fuzzyMatch(test.pattern, test.text)
+		if ok != test.ok || !reflect.DeepEqual(positions, test.positions) {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, %v, want %v, %v",
+				test.pattern, test.text, positions, ok, test.positions, test.ok)
+		}
+	}
+}
+
+func TestFuzzyMatchScores(t *testing.T) {
+	tests := []struct {
+		pattern string
+		better  string
+		worse   string
+	}{
+		// the starts of words and of the text count more
+		{"ff", "File Finder", "Giffy"},
+		{"tb", "Thunderbird", "Text Editor lab"},
+		{"vs", "Visual Studio Code", "Evince Viewer for PDFs"},
+		{"gc", "GnomeCalculator", "Gnucash"},
+		{"term", "Terminal", "Alacritty Term"},
+
+		// consecutive characters count more than scattered ones
+		{"edit", "Text Editor", "Speed Indicator"},
+
+		// exact matches count more than typos
+		{"firefox", "Firefox", "Firefix"},
+	}
+
+	for _, test := range tests {
+		better, _, ok := fuzzyMatch(test.pattern, test.better)
+		if !ok {
+			t.Errorf("%q does not match %q", test.pattern, test.better)
+			continue
+		}
+		worse, _, ok := fuzzyMatch(test.pattern, test.worse)
+		if !ok {
+			t.Errorf("%q does not match %q", test.pattern, test.worse)
+			continue
+		}
+
+		if better <= worse {
+			t.Errorf("%q scores %d for %q, not more than %d for %q", test.pattern, better, test.better, worse, test.worse)
+		}
+	}
+}
+
+func TestFuzzyMatchTypos(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		text      string
+		ok        bool
+		positions []int
+	}{
+		// swapped, replaced and extra characters (missing ones are not typos)
+		{"fierfox", "Firefox", true, []int{0, 1, 2, 3, 4, 5, 6}},
+		{"firwfox", "Firefox", true, []int{0, 1, 2, 3, 4, 5, 6}},
+		{"firefoxx", "Firefox", true, []int{0, 1, 2, 3, 4, 5, 6}},
+		{"frefox", "Firefox", true, []int{0, 2, 3, 4, 5, 6}},
+		{"gimmp", "GIMP", true, []int{0, 1, 2, 3}},
+
+		// only at the start of words
+		{"brwoser", "Web Browser", true, []int{4, 5, 6, 7, 8, 9, 10}},
+		{"rwoser", "Web Browser", false, nil},
+
+		// longer patterns tolerate more typos
+		{"thnuderbrid", "Thunderbird", true, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10}},
+		{"fiarfax", "Firefox", false, nil},
+
+		// short patterns would match anything
+		{"vmi", "Vim", false, nil},
+	}
+
+	for _, test := range tests {
+		_, positions, ok := fuzzyMatch(test.pattern, test.text)
+		if ok != test.ok || !reflect.DeepEqual(positions, test.positions) {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, %v, want %v, %v",
+				test.pattern, test.text, positions, ok, test.positions, test.ok)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"firefox", "firefox", 0},
+		{"firefox", "fierfox", 1},
+		{"firefox", "firfox", 1},
+		{"firefox", "firefoxx", 1},
+		{"firefox", "firewox", 1},
+		{"ca", "abc", 3},
+		{"kitten", "sitting", 3},
+	}
+
+	for _, test := range tests {
+		if got := editDistance([]rune(test.a), []rune(test.b)); got != test.distance {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", test.a, test.b, got, test.distance)
+		}
+	}
+}
+
+func TestIsWordStart(t *testing.T) {
+	text := []rune("GnomeCalc 2-web_app")
+	var starts []int
+	for i := range text {
+		if isWordStart(text, i) {
+			starts = append(starts, i)
+		}
+	}
+
+	if want := []int{0, 5, 10, 12, 16}; !reflect.DeepEqual(starts, want) {
+		t.Errorf("words start at %v, want %v", starts, want)
+	}
+}
+
+// The names of the results in order
+func resultNames(results []SearchResult) (names []string) {
+	for _, result := range results {
+		names = append(names, result.Entry.Name)
+	}
+	return names
+}
+
+func TestSearchFields(t *testing.T) {
+	entries := []*SearchEntry{
+		{Name: "Files", GenericName: "File Manager", Keywords: []string{"folder", "explorer"}, Binary: "nautilus"},
+		{Name: "Web", GenericName: "Web Browser", Keywords: []string{"internet", "www"}, Binary: "epiphany"},
+		{Name: "Terminal", Keywords: []string{"shell", "prompt"}, Binary: "gnome-terminal"},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"files", []string{"Files"}},
+		{"  web  ", []string{"Web"}},
+
+		// the generic name, keywords and the program
+		{"manager", []string{"Files"}},
+		{"browser", []string{"Web"}},
+		{"explorer", []string{"Files"}},
+		{"shell", []string{"Terminal"}},
+		{"nautilus", []string{"Files"}},
+		{"epiphany", []string{"Web"}},
+
+		{"nothing like it", nil},
+	}
+
+	for _, test := range tests {
+		if got := resultNames(Search(entries, test.query, 0)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q found %q, want %q", test.query, got, test.want)
+		}
+	}
+}
+
+func TestSearchHighlights(t *testing.T) {
+	entries := []*SearchEntry{
+		{Name: "Files", Binary: "nautilus"},
+	}
+
+	// only matches of the name are highlighted
+	if results := Search(entries, "fil", 0); len(results) != 1 || !reflect.DeepEqual(results[0].Highlights, []int{0, 1, 2}) {
+		t.Errorf("got %+v for a match of the name", results)
+	}
+	if results := Search(entries, "nautilus", 0); len(results) != 1 || results[0].Highlights != nil {
+		t.Errorf("got %+v for a match of the program", results)
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	entries := []*SearchEntry{
+		{Name: "Text Editor", Binary: "gedit"},
+		{Name: "Terminal", Binary: "foot"},
+		{Name: "Telegram", Binary: "telegram-desktop", Frecency: 3},
+		{Name: "Tetris", Binary: "tetris", Frecency: 3},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// matches that score the same are ranked by usage, then by name
+		{"te", []string{"Telegram", "Tetris", "Terminal", "Text Editor"}},
+
+		// usage does not make up for a much better match
+		{"terminal", []string{"Terminal"}},
+		{"tex", []string{"Text Editor"}},
+
+		// without a query, everything by usage, then by name
+		{"", []string{"Telegram", "Tetris", "Terminal", "Text Editor"}},
+	}
+
+	for _, test := range tests {
+		if got := resultNames(Search(entries, test.query, 0)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q found %q, want %q", test.query, got, test.want)
+		}
+	}
+
+	// the limit cuts off the worst results
+	if got := resultNames(Search(entries, "", 2)); !reflect.DeepEqual(got, []string{"Telegram", "Tetris"}) {
+		t.Errorf("the first 2 results are %q", got)
+	}
+}
+
+func TestSearchUsage(t *testing.T) {
+	// "co" matches the start of a word in both, but scattered in one of them
+	entries := []*SearchEntry{
+		{Name: "Visual Studio Code"},
+		{Name: "Calendar Tool"},
+	}
+
+	got := resultNames(Search(entries, "co", 0))
+	if want := []string{"Visual Studio Code", "Calendar Tool"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("found %q without usage, want %q", got, want)
+	}
+
+	// usage makes up for a slightly worse match
+	entries[1].Frecency = 6
+	got = resultNames(Search(entries, "co", 0))
+	if want := []string{"Calendar Tool", "Visual Studio Code"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("found %q with usage, want %q", got, want)
+	}
+}
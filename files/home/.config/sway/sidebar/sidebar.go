@@ -8,21 +8,22 @@ package main
 
 import (
 	"bufio"
-	"crypto/md5"
-	"encoding/binary"
 	"encoding/csv"
-	"encoding/hex"
 	"errors"
+	"flag"
 	"fmt"
 	"image"
-	"io"
-	"math"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 	"unsafe"
 
 	"github.com/veandco/go-sdl2/sdl"
@@ -39,9 +40,6 @@ var display_size Vector
 
 var background_color uint32
 
-// The path to the folder containing images of the current desktops
-const DESKTOP_IMAGES_PATH = "~/.config/sway/dimgs/"
-
 /*
 ##############################################################
 # Section: Basic Types and functions
@@ -53,6 +51,21 @@ type Vector struct {
 	y int32
 }
 
+// Adds two vectors, e.g. a position and an offset
+func (vec Vector) add(other Vector) Vector {
+	return Vector{vec.x + other.x, vec.y + other.y}
+}
+
+// Subtracts a vector, e.g. to get a position relative to another one
+func (vec Vector) sub(other Vector) Vector {
+	return Vector{vec.x - other.x, vec.y - other.y}
+}
+
+// Checks if a point is inside of the rectangle from (0, 0) to size
+func (vec Vector) inside(size Vector) bool {
+	return vec.x >= 0 && vec.y >= 0 && vec.x < size.x && vec.y < size.y
+}
+
 type FractionVector struct {
 	x float32
 	y float32
@@ -69,70 +82,33 @@ const (
 	BOTTOM Align = 2
 )
 
-// text sizes
-const (
-	TITLE     int = 128
-	SUBTITLE  int = 64
-	HEADER    int = 32
-	SUBHEADER int = 24
-	TEXT      int = 16
-	SUBTEXT   int = 14
-)
-
 func UInt32ToColor(ui uint32) (color sdl.Color) {
 	bytes := (*[4]byte)(unsafe.Pointer(&ui))[:]
 	return sdl.Color{R: bytes[2], G: bytes[1], B: bytes[0], A: bytes[3]}
 }
 
+// The reverse of UInt32ToColor
+func ColorToUInt32(color sdl.Color) (ui uint32) {
+	return uint32(color.A)<<24 | uint32(color.R)<<16 | uint32(color.G)<<8 | uint32(color.B)
+}
+
+// Converts an image into a surface with an alpha channel,
+// so transparent parts (of icons for example) are blended onto the background
 func ImgTosurface(img image.Image) (surface *sdl.Surface, err error) {
 	// Credit to https://github.com/veandco/go-sdl2/issues/116#issuecomment-96056082
-	rgba := image.NewRGBA(img.Bounds())
-	w, h := img.Bounds().Max.X, img.Bounds().Max.Y
-	s, err := sdl.CreateRGBSurface(0, int32(w), int32(h), 32, 0, 0, 0, 0)
+	bounds := img.Bounds()
+	s, err := sdl.CreateRGBSurfaceWithFormat(0, int32(bounds.Dx()), int32(bounds.Dy()), 32, uint32(sdl.PIXELFORMAT_RGBA32))
 	if err != nil {
 		return s, err
 	}
-	rgba.Pix = s.Pixels()
 
-	for y := 0; y < h; y += 1 {
-		for x := 0; x < w; x += 1 {
-			c := img.At(x, y)
-			rgba.Set(x, y, c)
-		}
-	}
+	// RGBA32 has the same byte order as image.RGBA
+	rgba := &image.RGBA{Pix: s.Pixels(), Stride: int(s.Pitch), Rect: image.Rect(0, 0, bounds.Dx(), bounds.Dy())}
+	draw.Draw(rgba, rgba.Rect, img, bounds.Min, draw.Src)
 
 	return s, nil
 }
 
-func resizeSurface(surf *sdl.Surface, newsize Vector) (resizedsurf *sdl.Surface, err error) {
-	resizedsurf, err = sdl.CreateRGBSurface(0, newsize.x, newsize.y, 32, 0, 0, 0, 0)
-	if err != nil {
-		return resizedsurf, err
-	}
-
-	rgba := image.NewRGBA(image.Rect(0, 0, int(newsize.x), int(newsize.y)))
-	rgba.Pix = resizedsurf.Pixels()
-
-	pixels := surf.Pixels()
-
-	// the factor from newscale to oldscale
-	scalex := float32(surf.W) / float32(newsize.x)
-	scaley := float32(surf.H) / float32(newsize.y)
-
-	for y := int32(0); y < newsize.y; y += 1 {
-		for x := int32(0); x < newsize.x; x += 1 {
-			// the byte index of the color currently looking at
-			//		*					line 											  *  			 pixel in line (x)					*
-			index := int32((float32(surf.BytesPerPixel()) * (float32(surf.W*y) * scaley)) + (float32(int32(surf.BytesPerPixel())*x) * scalex))
-
-			// set the pixel using magic
-			rgba.Set(int(x), int(y), UInt32ToColor(binary.BigEndian.Uint32(pixels[index:index+4])))
-		}
-	}
-
-	return resizedsurf, err
-}
-
 /*
 ###############################################################
 # Section: Initialization
@@ -161,6 +137,13 @@ func Initialize() {
 	}
 
 	display_size = Vector{bounds.W, bounds.H}
+
+	// Connect to sway. The sidebar still works without it,
+	// so only report the error
+	sway_ipc, err = ConnectIPC()
+	if err != nil {
+		fmt.Println(err)
+	}
 }
 
 /*
@@ -170,83 +153,347 @@ func Initialize() {
 */
 
 // Every type with a position and scale is considered an item.
+// Items draw through a Canvas, from (0, 0) to their size.
+// Items are dirty when they changed since they were drawn last, the window is only drawn again then.
 type Item interface {
-	Draw(*sdl.Surface) error
+	Draw(Canvas) error
 	GetPosition() Vector
 	SetPosition(Vector)
 	GetSize() Vector
 	SetSize(Vector)
+	Dirty() bool
+}
+
+// Items that change by themselves, like animations.
+// While one of them is animating, the window is drawn on every frame (at most MAX_FPS).
+type Animated interface {
+	Animating() bool
 }
 
 // This is the first (and the most important) item.
 // It is used to group other items.
+// Without a layout, the items keep the positions and sizes they are given.
 type Container struct {
 	position Vector
 	size     Vector
-	items    map[string]Item
+	dirty    bool
+
+	layout Layout
+
+	// the container this one is an item of (nil for the main container)
+	parent *Container
+
+	// in the order they are placed in (and drawn in, unless their z-index says otherwise)
+	children []*ContainerChild
+
+	// the same children by name
+	by_name map[string]*ContainerChild
+}
+
+// An item of a container
+type ContainerChild struct {
+	name string
+	item Item
+
+	// how the layout places the item
+	params LayoutParams
+
+	// items with a higher z-index are drawn on top of the others, equal ones in their order
+	z int
 }
 
 // Move the item to a pixel position
 func (cont *Container) MoveItem(item string, pos Vector) {
-	cont.items[item].SetPosition(pos)
+	cont.GetItem(item).SetPosition(pos)
 }
 
 // Move the item to a fraction of the parent container size
 func (cont *Container) MoveItemToFraction(item string, pos FractionVector) {
-	cont.items[item].SetPosition(Vector{int32(pos.x * float32(cont.size.x)), int32(pos.y * float32(cont.size.y))})
+	cont.GetItem(item).SetPosition(Vector{int32(pos.x * float32(cont.size.x)), int32(pos.y * float32(cont.size.y))})
 }
 
 // Resize an Item to a specific pixel size
 func (cont *Container) ResizeItem(item string, size Vector) {
-	cont.items[item].SetSize(size)
+	cont.GetItem(item).SetSize(size)
 }
 
 // Resize an Item to a fraction of the parent container size
 func (cont *Container) ResizeItemToFraction(item string, size FractionVector) {
-	cont.items[item].SetSize(Vector{int32(size.x * float32(cont.size.x)), int32(size.y * float32(cont.size.y))})
+	cont.GetItem(item).SetSize(Vector{int32(size.x * float32(cont.size.x)), int32(size.y * float32(cont.size.y))})
 }
 
 // draw a container
-// The container will let each item draw onto its own surface and then draw that onto the main surface
-func (cont *Container) Draw(surf *sdl.Surface) (err error) {
+// Each item is drawn at its position (relative to the container) onto the background,
+// everything outside of the item and of the container is cut off.
+func (cont *Container) Draw(canvas Canvas) (err error) {
+	cont.dirty = false
+
+	// the items may need a different size since the last time
+	if cont.layout != nil {
+		cont.layout.Arrange(cont)
+	}
 
-	// let each item draw onto the surface
-	for _, val := range cont.items {
-		isurface, err := sdl.CreateRGBSurface(0, val.GetSize().x, val.GetSize().y, 32, 0, 0, 0, 0)
-		if err != nil {
-			return err
-		}
+	canvas.Save()
+	defer canvas.Restore()
+	canvas.Clip(Vector{0, 0}, cont.size)
+
+	for _, child := range cont.drawOrder() {
+		val := child.item
+
+		pos := val.GetPosition()
+		size := val.GetSize()
+
+		// an item without a size (most likely the layout gave it no space) draws onto nothing,
+		// so it is not dirty anymore and does not make the window redraw all the time
+		canvas.Save()
+		canvas.Translate(pos)
+		canvas.Clip(Vector{0, 0}, size)
 
 		// also apply background color
-		isurface.FillRect(nil, background_color)
+		canvas.FillRect(Vector{0, 0}, size, background_color)
+		err = val.Draw(canvas)
 
-		err = val.Draw(isurface)
+		canvas.Restore()
 		if err != nil {
 			return err
 		}
+	}
 
-		pos := val.GetPosition()
-		size := val.GetSize()
+	return nil
+}
+
+// Gets the children in the order they are drawn in: by z-index, then in their order
+func (cont *Container) drawOrder() (children []*ContainerChild) {
+	children = append(children, cont.children...)
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].z < children[j].z
+	})
+	return children
+}
+
+// Add an item to the container. A new item goes after the others, a replaced one keeps its place.
+func (cont *Container) AddItem(name string, item Item) {
+	cont.InsertItem(name, item, len(cont.children))
+}
+
+// Add an item to the container, with the params its layout places it by
+func (cont *Container) AddItemWithParams(name string, item Item, params LayoutParams) {
+	cont.AddItem(name, item)
+	cont.by_name[name].params = params
+}
+
+// Inserts an item at an index of the order (like AddItem, a replaced item keeps its place)
+func (cont *Container) InsertItem(name string, item Item, index int) {
+	cont.adopt(item)
+
+	if child, ok := cont.by_name[name]; ok {
+		if child.item != item {
+			cont.release(child.item)
+		}
+		child.item = item
+		cont.dirty = true
+		return
+	}
+
+	if cont.by_name == nil {
+		cont.by_name = make(map[string]*ContainerChild)
+	}
 
-		// draw the item surface onto the container surface
-		src_rect := sdl.Rect{X: 0, Y: 0, W: size.x, H: size.y}
-		dst_rect := sdl.Rect{X: pos.x, Y: pos.y, W: pos.x + size.x, H: pos.y + size.y}
-		isurface.Blit(&src_rect, surf, &dst_rect)
+	child := &ContainerChild{name: name, item: item, params: DEFAULT_LAYOUT_PARAMS}
+	cont.insertChild(child, index)
+	cont.by_name[name] = child
+}
 
-		isurface.Free()
+// Makes a container that becomes an item of this one know its parent
+func (cont *Container) adopt(item Item) {
+	if sub, ok := item.(*Container); ok {
+		sub.parent = cont
 	}
+}
 
-	return nil
+// Makes a container that is no longer an item of this one forget its parent
+func (cont *Container) release(item Item) {
+	if sub, ok := item.(*Container); ok && sub.parent == cont {
+		sub.parent = nil
+	}
 }
 
-// Add an item to the container
-func (cont *Container) AddItem(name string, item Item) {
-	cont.items[name] = item
+// Puts a child at an index of the order, moving the ones after it back
+func (cont *Container) insertChild(child *ContainerChild, index int) {
+	if index < 0 {
+		index = 0
+	}
+	if index > len(cont.children) {
+		index = len(cont.children)
+	}
+
+	cont.children = append(cont.children, nil)
+	copy(cont.children[index+1:], cont.children[index:])
+	cont.children[index] = child
+
+	cont.dirty = true
+}
+
+// Inserts an item right before another one (at the end if there is no such item)
+func (cont *Container) InsertItemBefore(name string, item Item, before string) {
+	index := cont.IndexOf(before)
+	if index < 0 {
+		index = len(cont.children)
+	}
+	cont.InsertItem(name, item, index)
+}
+
+// Inserts an item right after another one (at the end if there is no such item)
+func (cont *Container) InsertItemAfter(name string, item Item, after string) {
+	index := cont.IndexOf(after)
+	if index < 0 {
+		index = len(cont.children) - 1
+	}
+	cont.InsertItem(name, item, index+1)
+}
+
+// Remove an item from the container
+func (cont *Container) RemoveItem(name string) {
+	index := cont.IndexOf(name)
+	if index < 0 {
+		return
+	}
+
+	cont.release(cont.children[index].item)
+
+	cont.children = append(cont.children[:index], cont.children[index+1:]...)
+	delete(cont.by_name, name)
+	cont.dirty = true
+}
+
+// Moves an item to another index of the order
+func (cont *Container) ReorderItem(name string, index int) {
+	from := cont.IndexOf(name)
+	if from < 0 {
+		return
+	}
+
+	child := cont.children[from]
+	cont.children = append(cont.children[:from], cont.children[from+1:]...)
+	cont.insertChild(child, index)
+}
+
+// Moves an item right before another one
+func (cont *Container) MoveItemBefore(name string, before string) {
+	if name == before || cont.IndexOf(before) < 0 {
+		return
+	}
+
+	index := cont.IndexOf(before)
+	if cont.IndexOf(name) < index {
+		index--
+	}
+	cont.ReorderItem(name, index)
+}
+
+// Moves an item right after another one
+func (cont *Container) MoveItemAfter(name string, after string) {
+	if name == after || cont.IndexOf(after) < 0 {
+		return
+	}
+
+	index := cont.IndexOf(after)
+	if cont.IndexOf(name) > index {
+		index++
+	}
+	cont.ReorderItem(name, index)
+}
+
+// Gets the index of an item in the order (-1 if there is no such item)
+func (cont *Container) IndexOf(name string) (index int) {
+	for i, child := range cont.children {
+		if child.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Sets the z-index of an item (0 by default)
+func (cont *Container) SetZIndex(name string, z int) {
+	if child, ok := cont.by_name[name]; ok {
+		child.z = z
+		cont.dirty = true
+	}
+}
+
+// Gets the names of the items, in their order
+func (cont *Container) ItemNames() (names []string) {
+	for _, child := range cont.children {
+		names = append(names, child.name)
+	}
+	return names
+}
+
+// Calls fn with every item, in their order. Items must not be added or removed in fn.
+func (cont *Container) ForEachItem(fn func(name string, item Item)) {
+	for _, child := range cont.children {
+		fn(child.name, child.item)
+	}
+}
+
+// Gets the number of items
+func (cont *Container) Len() int {
+	return len(cont.children)
+}
+
+// Sets how the items are placed (nil to place them by hand)
+func (cont *Container) SetLayout(layout Layout) {
+	cont.layout = layout
+	cont.dirty = true
+}
+
+// Changes how the layout places an item
+func (cont *Container) SetLayoutParams(name string, params LayoutParams) {
+	if child, ok := cont.by_name[name]; ok {
+		child.params = params
+		cont.dirty = true
+	}
+}
+
+// Gets the size the items need (the current size without a layout)
+func (cont *Container) Measure() (size Vector) {
+	if cont.layout == nil {
+		return cont.size
+	}
+	return cont.layout.Measure(cont)
+}
+
+// Checks if the container or one of its items changed
+func (cont *Container) Dirty() bool {
+	if cont.dirty {
+		return true
+	}
+	for _, child := range cont.children {
+		if child.item.Dirty() {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks if one of the items is animating
+func (cont *Container) Animating() bool {
+	for _, child := range cont.children {
+		if animated, ok := child.item.(Animated); ok && animated.Animating() {
+			return true
+		}
+	}
+	return false
 }
 
-// Get an item from the container
+// Get an item from the container (nil if there is no such item)
 func (cont *Container) GetItem(name string) (item Item) {
-	return cont.items[name]
+	child, ok := cont.by_name[name]
+	if !ok {
+		return nil
+	}
+	return child.item
 }
 
 // Getters and setters
@@ -257,6 +504,7 @@ func (cont *Container) GetPosition() (position Vector) {
 
 func (cont *Container) SetPosition(position Vector) {
 	cont.position = position
+	cont.dirty = true
 }
 
 func (cont *Container) GetSize() (size Vector) {
@@ -265,6 +513,77 @@ func (cont *Container) GetSize() (size Vector) {
 
 func (cont *Container) SetSize(size Vector) {
 	cont.size = size
+	cont.dirty = true
+}
+
+/*
+########################
+# Subsection: Coordinates
+########################
+*/
+
+// The positions of items are relative to the container they are in, so (0, 0) is its top left corner.
+// Only containers added to other containers know where they are in the window,
+// items drawn by other items (like the rows of a ListView) are not part of the tree.
+
+// Converts a point of the container to window coordinates
+func (cont *Container) ToWindow(point Vector) Vector {
+	for c := cont; c != nil; c = c.parent {
+		point = point.add(c.position)
+	}
+	return point
+}
+
+// Converts a point of the window to coordinates of the container
+func (cont *Container) FromWindow(point Vector) Vector {
+	for c := cont; c != nil; c = c.parent {
+		point = point.sub(c.position)
+	}
+	return point
+}
+
+// Converts a point of an item of the container to window coordinates
+func (cont *Container) ItemToWindow(name string, point Vector) Vector {
+	item := cont.GetItem(name)
+	if item == nil {
+		return cont.ToWindow(point)
+	}
+	return cont.ToWindow(item.GetPosition().add(point))
+}
+
+// Finds the item of the container at a point of the container (the topmost one if they overlap).
+// Returns its name and the point relative to it, or an empty name if there is none.
+func (cont *Container) ChildAt(point Vector) (name string, item Item, local Vector) {
+	// the items are cut off at the edges
+	if !point.inside(cont.size) {
+		return "", nil, point
+	}
+
+	children := cont.drawOrder()
+	for i := len(children) - 1; i >= 0; i-- {
+		child := children[i]
+
+		local = point.sub(child.item.GetPosition())
+		if local.inside(child.item.GetSize()) {
+			return child.name, child.item, local
+		}
+	}
+
+	return "", nil, point
+}
+
+// Finds the innermost item at a point of the container, going into the containers in it.
+// Returns the item and the point relative to it, or nil if there is none.
+func (cont *Container) ItemAt(point Vector) (item Item, local Vector) {
+	_, item, local = cont.ChildAt(point)
+
+	if sub, ok := item.(*Container); ok {
+		if inner, inner_local := sub.ItemAt(local); inner != nil {
+			return inner, inner_local
+		}
+	}
+
+	return item, local
 }
 
 /*
@@ -283,35 +602,38 @@ type Label struct {
 	position Vector
 	size     Vector
 	text     string
-	textsize int
+	font     FontRole
 	valign   Align
 	halign   Align
-	color    uint32
-	bgcolor  uint32
-	bold     bool
-}
+	color    ColorRole
+	bgcolor  ColorRole
 
-// Draw the item onto the parent surface
-func (label *Label) Draw(surf *sdl.Surface) (err error) {
+	// rune indices of characters drawn in hlcolor instead (e.g. search matches)
+	highlights []int
+	hlcolor    ColorRole
 
-	var font *ttf.Font
+	dirty bool
+}
 
-	// load font
-	if label.bold {
-		font, err = ttf.OpenFont("/usr/share/fonts/TTF/DejaVuSans-Bold.ttf", label.textsize)
-	} else {
-		font, err = ttf.OpenFont("/usr/share/fonts/TTF/DejaVuSans.ttf", label.textsize)
+// Draw the item onto the canvas
+func (label *Label) Draw(canvas Canvas) (err error) {
+	label.dirty = false
+
+	// there is nothing to draw
+	if label.text == "" {
+		return nil
 	}
+
+	font, err := getFont(label.font)
 	if err != nil {
 		return err
 	}
 
-	// Render text to surface
-	text_surface, err := font.RenderUTF8Shaded(label.text, UInt32ToColor(label.color), UInt32ToColor(label.bgcolor))
+	w, h, err := font.SizeUTF8(label.text)
 	if err != nil {
 		return err
 	}
-	defer text_surface.Free()
+	text_size := Vector{int32(w), int32(h)}
 
 	// Calculate vertical and horizontal position on surface
 	var coordinate_x int32
@@ -321,26 +643,64 @@ func (label *Label) Draw(surf *sdl.Surface) (err error) {
 	case LEFT:
 		coordinate_x = 0
 	case CENTER:
-		coordinate_x = (int32(label.size.x) - text_surface.W) / 2
+		coordinate_x = (int32(label.size.x) - text_size.x) / 2
 	case RIGHT:
-		coordinate_x = int32(label.size.x) - text_surface.W
+		coordinate_x = int32(label.size.x) - text_size.x
 	}
 
 	switch label.valign {
 	case TOP:
 		coordinate_y = 0
 	case CENTER:
-		coordinate_y = (int32(label.size.y) - text_surface.H) / 2
+		coordinate_y = (int32(label.size.y) - text_size.y) / 2
 	case BOTTOM:
-		coordinate_y = int32(label.size.y) - text_surface.H
+		coordinate_y = int32(label.size.y) - text_size.y
 	}
 
-	dst_rect := sdl.Rect{X: coordinate_x, Y: coordinate_y, W: coordinate_x + text_surface.W, H: coordinate_y + text_surface.H}
-
-	surf.FillRect(nil, background_color)
+	position := Vector{coordinate_x, coordinate_y}
 
 	// Draw onto final surface (Text aligned)
-	text_surface.Blit(&sdl.Rect{X: 0, Y: 0, W: text_surface.W, H: text_surface.H}, surf, &dst_rect)
+	canvas.FillRect(position, text_size, label.bgcolor.RGB())
+	return label.drawText(canvas, font, position)
+}
+
+// Draws the text, with the highlighted characters in their own color
+func (label *Label) drawText(canvas Canvas, font *LoadedFont, position Vector) (err error) {
+	if len(label.highlights) == 0 {
+		return canvas.DrawText(label.text, font, label.color.RGB(), position)
+	}
+
+	highlighted := make(map[int]bool)
+	for _, i := range label.highlights {
+		highlighted[i] = true
+	}
+
+	// split the text into runs of (not) highlighted characters
+	runes := []rune(label.text)
+	for start := 0; start < len(runes); {
+		end := start + 1
+		for end < len(runes) && highlighted[end] == highlighted[start] {
+			end++
+		}
+
+		color := label.color
+		if highlighted[start] {
+			color = label.hlcolor
+		}
+
+		// measuring the whole prefix keeps the kerning intact
+		x, _, err := font.SizeUTF8(string(runes[:start]))
+		if err != nil {
+			return err
+		}
+
+		err = canvas.DrawText(string(runes[start:end]), font, color.RGB(), Vector{position.x + int32(x), position.y})
+		if err != nil {
+			return err
+		}
+
+		start = end
+	}
 
 	return nil
 }
@@ -353,6 +713,7 @@ func (label *Label) GetPosition() (position Vector) {
 
 func (label *Label) SetPosition(newposition Vector) {
 	label.position = newposition
+	label.dirty = true
 }
 
 func (label *Label) GetSize() (size Vector) {
@@ -361,6 +722,39 @@ func (label *Label) GetSize() (size Vector) {
 
 func (label *Label) SetSize(newsize Vector) {
 	label.size = newsize
+	label.dirty = true
+}
+
+func (label *Label) SetText(text string) {
+	label.text = text
+	label.dirty = true
+}
+
+func (label *Label) SetColor(color ColorRole) {
+	label.color = color
+	label.dirty = true
+}
+
+func (label *Label) Dirty() bool {
+	return label.dirty
+}
+
+// Gets the size of the text
+func (label *Label) Measure() (size Vector) {
+	font, err := getFont(label.font)
+	if err != nil {
+		return Vector{0, 0}
+	}
+
+	if label.text == "" {
+		return Vector{0, int32(font.Height())}
+	}
+
+	w, h, err := font.SizeUTF8(label.text)
+	if err != nil {
+		return Vector{0, int32(font.Height())}
+	}
+	return Vector{int32(w), int32(h)}
 }
 
 /*
@@ -372,16 +766,17 @@ func (label *Label) SetSize(newsize Vector) {
 type Texture struct {
 	position Vector
 	size     Vector
-	texture  *sdl.Surface
+	picture  *Picture
+	dirty    bool
 }
 
-// Draw the item onto the parent surface
-func (tex *Texture) Draw(surf *sdl.Surface) (err error) {
-	src_rect := sdl.Rect{X: 0, Y: 0, W: tex.size.x, H: tex.size.y}
-	dst_rect := sdl.Rect{X: 0, Y: 0, W: tex.size.x, H: tex.size.y}
-	tex.texture.Blit(&src_rect, surf, &dst_rect)
-
-	return nil
+// Draw the item onto the canvas (a smaller item cuts the picture off)
+func (tex *Texture) Draw(canvas Canvas) (err error) {
+	tex.dirty = false
+	if tex.picture == nil {
+		return nil
+	}
+	return canvas.DrawPicture(tex.picture, Vector{0, 0})
 }
 
 // Getters and setters
@@ -392,6 +787,7 @@ func (tex *Texture) GetPosition() (position Vector) {
 
 func (tex *Texture) SetPosition(position Vector) {
 	tex.position = position
+	tex.dirty = true
 }
 
 func (tex *Texture) GetSize() (size Vector) {
@@ -400,6 +796,19 @@ func (tex *Texture) GetSize() (size Vector) {
 
 func (tex *Texture) SetSize(size Vector) {
 	tex.size = size
+	tex.dirty = true
+}
+
+func (tex *Texture) Dirty() bool {
+	return tex.dirty
+}
+
+// Gets the size of the texture
+func (tex *Texture) Measure() (size Vector) {
+	if tex.picture == nil {
+		return Vector{0, 0}
+	}
+	return tex.picture.Size()
 }
 
 /*
@@ -411,13 +820,14 @@ func (tex *Texture) SetSize(size Vector) {
 type Unicolor struct {
 	position Vector
 	size     Vector
-	color    uint32
+	color    ColorRole
+	dirty    bool
 }
 
-// Draw the item onto the parent surface
-func (unic *Unicolor) Draw(surf *sdl.Surface) (err error) {
-	rect := sdl.Rect{X: 0, Y: 0, W: unic.size.x, H: unic.size.y}
-	return surf.FillRect(&rect, unic.color)
+// Draw the item onto the canvas
+func (unic *Unicolor) Draw(canvas Canvas) (err error) {
+	unic.dirty = false
+	return canvas.FillRect(Vector{0, 0}, unic.size, unic.color.RGB())
 }
 
 // Getters and setters
@@ -428,6 +838,7 @@ func (unic *Unicolor) GetPosition() (position Vector) {
 
 func (unic *Unicolor) SetPosition(position Vector) {
 	unic.position = position
+	unic.dirty = true
 }
 
 func (unic *Unicolor) GetSize() (size Vector) {
@@ -436,6 +847,11 @@ func (unic *Unicolor) GetSize() (size Vector) {
 
 func (unic *Unicolor) SetSize(size Vector) {
 	unic.size = size
+	unic.dirty = true
+}
+
+func (unic *Unicolor) Dirty() bool {
+	return unic.dirty
 }
 
 /*
@@ -450,56 +866,168 @@ type WindowHandler interface {
 	HandleEvent(sdl.Event)
 }
 
-func CreateWindow(position Vector, size Vector, bgcolor uint32, handler WindowHandler) (err error) {
+// Gets the text of a text input event
+func GetInputText(event *sdl.TextInputEvent) (text string) {
+	// the text is null terminated
+	for i, b := range event.Text {
+		if b == 0 {
+			return string(event.Text[:i])
+		}
+	}
+	return string(event.Text[:])
+}
+
+// Runs a sway command, if sway can be reached
+func RunSwayCommand(command string) (err error) {
+	if sway_ipc == nil {
+		return fmt.Errorf("not connected to sway, cannot run %q", command)
+	}
+
+	_, err = sway_ipc.RunCommand(command)
+	return err
+}
+
+// Quotes an argument of a sway command (like a workspace name).
+// Sway only knows \" and \\ as escapes, everything else is taken as it is.
+func swayQuote(arg string) (quoted string) {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// The window is only drawn when an item changed (or is animating), at most MAX_FPS times a second
+const MAX_FPS = 60
+
+// Without events, the handler is updated every IDLE_TIMEOUT milliseconds.
+// Goroutines can wake the window earlier with WakeWindow.
+const IDLE_TIMEOUT = 500
+
+// Makes the main loop run (and update the handler) right away.
+// Unlike the rest of sdl, this can be used from any goroutine.
+func WakeWindow() {
+	sdl.PushEvent(&sdl.UserEvent{Type: sdl.USEREVENT})
+}
+
+// The title of the windows. The sway config matches it, and the capture daemon leaves them out of its images.
+const WINDOW_TITLE = "Sidebar"
+
+func CreateWindow(position Vector, size Vector, bgcolor ColorRole, handler WindowHandler) (err error) {
 	// This variable will will determine wether the window is running or not
 	running := true
 
-	// the main container
-	cont := Container{Vector{0, 0}, size, make(map[string]Item)}
+	// the main container, its coordinates are those of the window
+	cont := Container{position: Vector{0, 0}, size: size}
 
-	// create an sdl window for the window struct instance
-	window, err := sdl.CreateWindow("Sidebar", position.x, position.y,
-		cont.size.x, cont.size.y, sdl.WINDOW_POPUP_MENU)
+	// create an sdl window for the window struct instance.
+	// Popup menus never get keyboard focus, so this has to be a normal (borderless) window.
+	// Sway floats it at the left edge (see the sway config).
+	window, err := sdl.CreateWindow(WINDOW_TITLE, position.x, position.y,
+		cont.size.x, cont.size.y, sdl.WINDOW_SHOWN|sdl.WINDOW_BORDERLESS|sdl.WINDOW_SKIP_TASKBAR)
 	if err != nil {
 		return err
 	}
 
+	// ask for keyboard focus and get typed characters as text input events,
+	// so keyboard layouts and compose keys work
+	window.Raise()
+	sdl.StartTextInput()
+	defer sdl.StopTextInput()
+
 	surface, err := window.GetSurface()
 	if err != nil {
 		return err
 	}
 	defer window.Destroy()
 	defer sdl.Quit()
+	defer closeFonts()
+
+	// the rows look up their icons while they are drawn, so the lookups are known only now
+	defer saveIconCache()
 
 	// Set the background color
-	surface.FillRect(nil, bgcolor)
-	background_color = bgcolor
+	background_color = bgcolor.RGB()
 
 	// Initialize the handler
 	handler.Init(&cont, &running)
 
+	// the first frame is always drawn
+	redraw := true
+	var last_frame time.Time
+
 	// The main loop
 	for running {
+		animating := cont.Animating()
+
+		// sleep until something happens. With something to draw, only until the next frame is due.
+		timeout := time.Duration(IDLE_TIMEOUT) * time.Millisecond
+		if redraw || animating || cont.Dirty() {
+			timeout = time.Second/MAX_FPS - time.Since(last_frame)
+		}
+
+		var event sdl.Event
+		if timeout > 0 {
+			// rounded up, so it does not wake up just before the frame is due
+			event = sdl.WaitEventTimeout(int((timeout + time.Millisecond - 1) / time.Millisecond))
+		} else {
+			event = sdl.PollEvent()
+		}
 
 		// Quit the program in case of exit event
-		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-			switch event.(type) {
+		for ; event != nil; event = sdl.PollEvent() {
+			switch ty := event.(type) {
 			case *sdl.QuitEvent:
 				fmt.Println("Exit signal received. Quitting...")
 				running = false
-				break
+			case *sdl.KeyboardEvent:
+				// escape dismisses every window
+				if ty.Type == sdl.KEYDOWN && ty.Keysym.Sym == sdl.K_ESCAPE {
+					running = false
+					break
+				}
+				handler.HandleEvent(event)
+			case *sdl.WindowEvent:
+				switch ty.Event {
+				// the old surface is gone
+				case sdl.WINDOWEVENT_SIZE_CHANGED:
+					surface, err = window.GetSurface()
+					if err != nil {
+						return err
+					}
+					cont.SetSize(Vector{ty.Data1, ty.Data2})
+					redraw = true
+				case sdl.WINDOWEVENT_SHOWN, sdl.WINDOWEVENT_EXPOSED:
+					redraw = true
+				}
+				handler.HandleEvent(event)
 			default:
 				handler.HandleEvent(event)
 			}
 		}
 
 		handler.Update()
-		cont.Draw(surface)
-		window.UpdateSurface()
-	}
 
-	return nil
-}
+		if !redraw && !animating && !cont.Dirty() {
+			continue
+		}
+
+		// frames are capped at MAX_FPS
+		if time.Since(last_frame) < time.Second/MAX_FPS {
+			continue
+		}
+
+		// removed items must not stay on the surface
+		canvas := NewSurfaceCanvas(surface)
+		canvas.FillRect(Vector{0, 0}, Vector{surface.W, surface.H}, background_color)
+		err = cont.Draw(canvas)
+		if err != nil {
+			fmt.Println(err)
+		}
+		window.UpdateSurface()
+
+		redraw = false
+		last_frame = time.Now()
+	}
+
+	return nil
+}
 
 /*
 ######################################################################################################
@@ -515,45 +1043,85 @@ func CreateWindow(position Vector, size Vector, bgcolor uint32, handler WindowHa
 ####################################################################
 */
 
-const DEF_BG_COLOR uint32 = 0x10171e
-const WHITE_COLOR uint32 = 0xffffff
+// sizes and counts can be changed in the config file, colors and fonts are part of the theme
+
+var SCREEN_FRACTION = 4
 
-const SCREEN_FRACTION = 4
+// the number of workspaces the desktop window shows without a connection to sway
+var DESKTOP_COUNT = 6
 
 func main() {
-	// initialize packages
-	Initialize()
+	theme := flag.String("theme", "", "the theme to use (dark, light, "+SWAY_THEME+" or the name of a file in "+filepath.Join(SIDEBAR_SUBDIR, THEMES_DIR)+")")
+	flag.Parse()
 
-	// 0 is the program itself, 1 is the first argument
+	// the first argument after the flags
 	var arg string
-	if len(os.Args) > 1 {
-		arg = os.Args[1]
+	if flag.NArg() > 0 {
+		arg = flag.Arg(0)
 	} else {
 		arg = "notspecified"
 	}
 
-	var handler WindowHandler
+	// modes without a window
+	switch arg {
+	case "capture-daemon":
+		err := runCaptureDaemon(flag.Args()[1:])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	case "render":
+		err := runRender(flag.Args()[1:], *theme)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// initialize packages
+	Initialize()
 
 	// Determine the type of window
-	switch arg {
+	handler, arg, err := getWindowHandler(arg, flag.Args())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// invalid settings keep their defaults, so the window still opens
+	err = loadConfig(arg, *theme)
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	CreateWindow(Vector{0, 0}, Vector{display_size.x / int32(SCREEN_FRACTION), display_size.y}, BACKGROUND, handler)
+}
+
+// Gets the handler of a window by its name, args are the arguments starting with the name.
+// Unknown names get the run window, window is the name of the one that is shown.
+func getWindowHandler(name string, args []string) (handler WindowHandler, window string, err error) {
+	switch name {
 	case "power":
-		handler = &PowerWindowHandler{}
+		return &PowerWindowHandler{}, name, nil
 	case "run":
-		handler = &RunWindowHandler{}
+		return &RunWindowHandler{}, name, nil
 	case "desktop":
-		handler = &DesktopWindowHandler{}
-	default:
-		handler = &RunWindowHandler{}
-	}
-
-	CreateWindow(Vector{0, 0}, Vector{display_size.x / SCREEN_FRACTION, display_size.y}, DEF_BG_COLOR, handler)
+		return &DesktopWindowHandler{}, name, nil
+	case "open":
+		if len(args) < 2 {
+			return nil, name, errors.New("usage: sidebar open <panel file or name>")
+		}
 
-	//path, err := getDataFilePath()
-	//if err != nil {
-	//panic(err)
-	//}
+		spec, err := loadPanelSpec(args[1])
+		if err != nil {
+			return nil, name, err
+		}
+		return &PanelWindowHandler{spec: spec}, name, nil
+	}
 
-	//fmt.Println(incrementDataFileEntry(path, "/usr/share/applications/firefox.desktop"))
+	return &RunWindowHandler{}, "run", nil
 }
 
 /*
@@ -562,28 +1130,78 @@ func main() {
 #################################################################
 */
 
+// An entry of the power menu
+type PowerOption struct {
+	key     string
+	text    string
+	command string // sway command
+}
+
+var power_options = []PowerOption{
+	{"e", "Exit sway", "exit"},
+	{"s", "Shut down", "exec poweroff"},
+	{"r", "Reboot", "exec reboot"},
+}
+
 type PowerWindowHandler struct {
 	cont *Container
 	exit *bool
+
+	selected int
 }
 
 func (pwh *PowerWindowHandler) Init(c *Container, e *bool) {
 	pwh.cont = c
 	pwh.exit = e
 
+	pwh.cont.SetLayout(&VBox{spacing: spacing()})
+
 	pwh.cont.AddItem("title", &Label{
-		position: Vector{0, 0},
-		size:     Vector{0, 0}, // will be resized later
-		text:     "Power",
-		textsize: 128,
-		valign:   CENTER,
-		halign:   CENTER,
-		color:    WHITE_COLOR,
-		bgcolor:  DEF_BG_COLOR,
-		bold:     false,
+		text:    "Power",
+		font:    TITLE_FONT,
+		valign:  CENTER,
+		halign:  CENTER,
+		color:   FOREGROUND,
+		bgcolor: BACKGROUND,
 	})
 
-	pwh.cont.ResizeItemToFraction("title", FractionVector{1.0, 0.1})
+	for _, option := range power_options {
+		pwh.cont.AddItem("option-"+option.key, &Label{
+			text:    "[" + option.key + "] " + option.text,
+			font:    HEADER_FONT,
+			valign:  CENTER,
+			halign:  CENTER,
+			color:   FOREGROUND,
+			bgcolor: BACKGROUND,
+		})
+	}
+
+	pwh.selectOption(0)
+}
+
+// Marks an option as selected
+func (pwh *PowerWindowHandler) selectOption(index int) {
+	pwh.selected = (index + len(power_options)) % len(power_options)
+
+	for i, option := range power_options {
+		label := pwh.cont.GetItem("option-" + option.key).(*Label)
+		if i == pwh.selected {
+			label.SetColor(ACCENT)
+		} else {
+			label.SetColor(MUTED)
+		}
+	}
+}
+
+// Runs an option and closes the window
+func (pwh *PowerWindowHandler) activate(index int) {
+	err := RunSwayCommand(power_options[index].command)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	*pwh.exit = false
 }
 
 func (pwh *PowerWindowHandler) Update() {
@@ -591,7 +1209,41 @@ func (pwh *PowerWindowHandler) Update() {
 }
 
 func (pwh *PowerWindowHandler) HandleEvent(event sdl.Event) {
-	return
+	switch ty := event.(type) {
+	case *sdl.TextInputEvent:
+		text := GetInputText(ty)
+		for i, option := range power_options {
+			if text == option.key {
+				pwh.activate(i)
+			}
+		}
+	case *sdl.KeyboardEvent:
+		if ty.Type != sdl.KEYDOWN {
+			return
+		}
+
+		switch ty.Keysym.Sym {
+		case sdl.K_UP:
+			pwh.selectOption(pwh.selected - 1)
+		case sdl.K_DOWN:
+			pwh.selectOption(pwh.selected + 1)
+		case sdl.K_RETURN:
+			pwh.activate(pwh.selected)
+		}
+	case *sdl.MouseButtonEvent:
+		if ty.Type != sdl.MOUSEBUTTONDOWN || ty.Button != sdl.BUTTON_LEFT {
+			return
+		}
+
+		// clicking an option runs it
+		name, _, _ := pwh.cont.ChildAt(pwh.cont.FromWindow(Vector{ty.X, ty.Y}))
+		for i, option := range power_options {
+			if name == "option-"+option.key {
+				pwh.selectOption(i)
+				pwh.activate(i)
+			}
+		}
+	}
 }
 
 /*
@@ -603,41 +1255,97 @@ func (pwh *PowerWindowHandler) HandleEvent(event sdl.Event) {
 type RunWindowHandler struct {
 	cont *Container
 	exit *bool
+
+	// all programs that can be launched
+	entries []*SearchEntry
+
+	query   string
+	results []SearchResult
+
+	// the icons of the programs by desktop file path
+	icons map[string]*Picture
 }
 
 func (rwh *RunWindowHandler) Init(c *Container, e *bool) {
 	rwh.cont = c
 	rwh.exit = e
+	rwh.icons = make(map[string]*Picture)
+
+	rwh.cont.SetLayout(&VBox{spacing: spacing()})
 
 	rwh.cont.AddItem("title", &Label{
-		position: Vector{0, 0},
-		size:     Vector{0, 0}, // will be resized later
-		text:     "Run",
-		textsize: 128,
-		valign:   CENTER,
-		halign:   CENTER,
-		color:    WHITE_COLOR,
-		bgcolor:  DEF_BG_COLOR,
-		bold:     false,
+		text:    "Run",
+		font:    TITLE_FONT,
+		valign:  CENTER,
+		halign:  CENTER,
+		color:   FOREGROUND,
+		bgcolor: BACKGROUND,
 	})
 
-	rwh.cont.ResizeItemToFraction("title", FractionVector{1.0, 0.1})
+	rwh.cont.AddItemWithParams("search", &TextInput{
+		font:        HEADER_FONT,
+		color:       FOREGROUND,
+		bgcolor:     BACKGROUND,
+		selcolor:    SELECTION,
+		placeholder: "Type to search",
+		on_change: func(text string) {
+			rwh.query = text
+			rwh.search()
+		},
+	}, LayoutParams{margin: Insets{0, spacing(), 0, spacing()}, halign: FILL, valign: FILL})
+
+	// the list takes the rest of the window
+	rwh.cont.AddItemWithParams("results", &ListView{
+		row_height: SEPARATOR_HEIGHT + 2*spacing() + resultTextHeight(),
+		source:     rwh,
+		render: func(index int, size Vector, selected bool) (Item, error) {
+			return rwh.getProgramInfoCont(rwh.results[index], size, selected)
+		},
+		bgcolor:     BACKGROUND,
+		selcolor:    ACCENT,
+		barcolor:    MUTED,
+		on_activate: rwh.launch,
+	}, LayoutParams{flex: 1, halign: FILL, valign: FILL})
+
+	rwh.entries = loadSearchEntries(desktop_file_paths, getFrecencyScores())
+	rwh.search()
+}
+
+// Searches for the current query and shows the results
+func (rwh *RunWindowHandler) search() {
+	rwh.results = Search(rwh.entries, rwh.query, 0)
+
+	// the best match is selected again
+	results := rwh.cont.GetItem("results").(*ListView)
+	results.Select(0)
+	results.Refresh()
+}
+
+// The results are the source of the list
+func (rwh *RunWindowHandler) Len() int {
+	return len(rwh.results)
+}
+
+// Launches the program of a result and closes the window
+func (rwh *RunWindowHandler) launch(index int) {
+	if index >= len(rwh.results) {
+		return
+	}
+	path := rwh.results[index].Entry.Path
 
-	program, err := rwh.getProgramInfoCont("/usr/share/applications/nvim.desktop")
+	err := launchDesktopFile(path)
 	if err != nil {
 		fmt.Println(err)
+		return
 	}
 
-	rwh.cont.AddItem("program", program)
-	rwh.cont.MoveItemToFraction("program", FractionVector{0, 0.1})
-
-	program2, err := rwh.getProgramInfoCont("/usr/share/applications/termite.desktop")
+	// remember the launch for the ranking
+	err = recordLaunch(path)
 	if err != nil {
 		fmt.Println(err)
 	}
 
-	rwh.cont.AddItem("program2", program2)
-	rwh.cont.MoveItemToFraction("program2", FractionVector{0, 0.1 + (float32(1) / float32(16))})
+	*rwh.exit = false
 }
 
 func (rwh *RunWindowHandler) Update() {
@@ -645,114 +1353,122 @@ func (rwh *RunWindowHandler) Update() {
 }
 
 func (rwh *RunWindowHandler) HandleEvent(event sdl.Event) {
-	switch ty := event.(type) {
-	// Does not capture keyboard event (possibly because of window type)
-	// TODO find some other method to get keyboard input
-	case *sdl.KeyboardEvent:
-		fmt.Printf("[%d ms] Keyboard\ttype:%d\tsym:%c\tmodifiers:%d\tstate:%d\trepeat:%d\n",
-			ty.Timestamp, ty.Type, ty.Keysym.Sym, ty.Keysym.Mod, ty.State, ty.Repeat)
+	// typing goes to the search box, everything else to the results
+	if rwh.cont.GetItem("search").(*TextInput).HandleEvent(event) {
+		return
 	}
-}
 
-// Gets a container containing info about a .desktop file
-func (rwh *RunWindowHandler) getProgramInfoCont(path string) (cont *Container, err error) {
-	cont = &Container{Vector{0, 0}, Vector{rwh.cont.size.x, rwh.cont.size.y / 16}, make(map[string]Item)}
+	results := rwh.cont.GetItem("results").(*ListView)
 
-	info, err := parseDesktopFile(path)
-	if err != nil {
-		return cont, err
-	}
+	// clicking a result launches it
+	if ty, ok := event.(*sdl.MouseButtonEvent); ok {
+		if ty.Type != sdl.MOUSEBUTTONDOWN || ty.Button != sdl.BUTTON_LEFT {
+			return
+		}
 
-	// separator bar
-	cont.AddItem("bar", &Unicolor{
-		position: Vector{0, 0},
-		size:     Vector{cont.size.x, 4},
-		color:    0x20272e,
-	})
+		item, local := rwh.cont.ItemAt(rwh.cont.FromWindow(Vector{ty.X, ty.Y}))
+		if item != Item(results) {
+			return
+		}
 
-	iconsize := rwh.cont.size.y / 18
+		if index := results.RowAt(local); index >= 0 {
+			results.Select(index)
+			rwh.launch(index)
+		}
+		return
+	}
 
-	var icon *sdl.Surface
+	results.HandleEvent(event)
+}
 
-	default_icon := func() {
-		icon, err = sdl.CreateRGBSurface(0, iconsize, iconsize, 32, 0, 0, 0, 0)
-		icon.FillRect(nil, DEF_BG_COLOR)
+// Gets a picture of the given size showing an icon of the icon theme
+func loadIconPicture(name string, size int32) (icon *Picture, err error) {
+	path := getIconResolver().Lookup(name, int(size), 1)
+	if path == "" {
+		return nil, fmt.Errorf("icon %q not found", name)
 	}
 
-	homepath, err := getHomePath()
+	img, err := decodeIconImage(path, int(size))
 	if err != nil {
-		return cont, err
+		return nil, err
 	}
 
-	// open the icon file. If anything fails,
-	// use an empty surface instead
-	// ugly code incoming
-	// TODO find way to get icon path (icons are in subdirectories of dirs below)
-	iconfile, err := os.Open("/usr/share/icons/" + info["Icon"])
-	if err != nil {
-		iconfile, err := os.Open(homepath + "/.local/share/icons/" + info["Icon"])
-		if err != nil {
-			default_icon()
-		} else {
-			img, _, err := image.Decode(iconfile)
-			if err != nil {
-				default_icon()
-			} else {
-				iconsurf, err := ImgTosurface(img)
-				if err != nil {
-					default_icon()
-				} else {
-					icon, err = resizeSurface(iconsurf, Vector{iconsize, iconsize})
-					if err != nil {
-						default_icon()
-					}
-				}
-			}
-		}
+	// vector icons already have the right size
+	bounds := img.Bounds()
+	if bounds.Dx() == int(size) && bounds.Dy() == int(size) {
+		return NewPicture(img), nil
 	}
 
-	// if iconfile was initialized, close it again
-	if iconfile != nil {
-		err = iconfile.Close()
+	return NewPicture(scaleImage(img, Vector{size, size})), nil
+}
+
+// Gets the height of the name and description of a result (the icons are as high)
+func resultTextHeight() int32 {
+	return fontHeight(HEADER_FONT) + fontHeight(BODY_FONT)
+}
+
+// Gets a container containing info about a search result
+func (rwh *RunWindowHandler) getProgramInfoCont(result SearchResult, size Vector, selected bool) (cont *Container, err error) {
+	cont = &Container{position: Vector{0, 0}, size: size, layout: &VBox{}}
+
+	info := result.Entry.Entry
+
+	// separator bar
+	cont.AddItemWithParams("bar", &Unicolor{
+		color: SEPARATOR,
+	}, LayoutParams{min: Vector{0, SEPARATOR_HEIGHT}, halign: FILL, valign: FILL})
+
+	iconsize := resultTextHeight()
+
+	// load the icon (only once per program). If anything fails,
+	// use an empty picture instead
+	icon, ok := rwh.icons[result.Entry.Path]
+	if !ok {
+		icon, err = loadIconPicture(info.Icon(), iconsize)
 		if err != nil {
-			return cont, err
+			icon, err = NewFilledPicture(Vector{iconsize, iconsize}, BACKGROUND.RGB()), nil
 		}
+		rwh.icons[result.Entry.Path] = icon
 	}
 
-	// icon of the program
-	cont.AddItem("icon", &Texture{
-		position: Vector{0, 8},
-		size:     Vector{iconsize, iconsize},
-		texture:  icon,
-	})
+	// icon and text next to each other, leaving room for the selection marker
+	row := &Container{layout: &HBox{padding: Insets{spacing(), spacing(), spacing(), MARKER_WIDTH}, spacing: spacing()}}
+	cont.AddItemWithParams("row", row, LayoutParams{flex: 1, halign: FILL, valign: FILL})
+
+	row.AddItemWithParams("icon", &Texture{
+		picture: icon,
+	}, LayoutParams{halign: LEFT, valign: CENTER})
+
+	text := &Container{layout: &VBox{}}
+	row.AddItemWithParams("text", text, LayoutParams{flex: 1, halign: FILL, valign: FILL})
 
 	// name of the program
-	cont.AddItem("title", &Label{
-		position: Vector{iconsize + 8, 8},
-		size:     Vector{0, 0}, // will be resized later
-		text:     info["Name"],
-		textsize: HEADER,
-		valign:   TOP,
-		halign:   LEFT,
-		color:    WHITE_COLOR,
-		bgcolor:  DEF_BG_COLOR,
-		bold:     false,
+	text.AddItem("title", &Label{
+		text:    result.Entry.Name,
+		font:    HEADER_FONT,
+		valign:  TOP,
+		halign:  LEFT,
+		color:   FOREGROUND,
+		bgcolor: BACKGROUND,
+
+		highlights: result.Highlights,
+		hlcolor:    ACCENT,
 	})
-	cont.ResizeItemToFraction("title", FractionVector{float32(16) / float32(18), 0.5})
-
-	// description of the program
-	cont.AddItem("description", &Label{
-		position: Vector{iconsize + 8, (cont.size.y / 2) + 8},
-		size:     Vector{0, 0}, // will be resized later
-		text:     info["Comment"],
-		textsize: SUBHEADER,
-		valign:   BOTTOM,
-		halign:   LEFT,
-		color:    0xa0a1a7,
-		bgcolor:  DEF_BG_COLOR,
-		bold:     false,
+
+	// description of the program (brighter for the selected one)
+	description_color := MUTED
+	if selected {
+		description_color = FOREGROUND
+	}
+
+	text.AddItem("description", &Label{
+		text:    info.Comment(),
+		font:    BODY_FONT,
+		valign:  BOTTOM,
+		halign:  LEFT,
+		color:   description_color,
+		bgcolor: BACKGROUND,
 	})
-	cont.ResizeItemToFraction("description", FractionVector{float32(16) / float32(18), 0.2})
 
 	return cont, err
 }
@@ -764,272 +1480,249 @@ func (rwh *RunWindowHandler) getProgramInfoCont(path string) (cont *Container, e
 */
 
 type DesktopWindowHandler struct {
-	cont *Container
-	exit *bool
+	cont       *Container
+	exit       *bool
+	workspaces []Workspace
+
+	// receives a value whenever sway reports a workspace change
+	changed chan struct{}
 }
 
 func (dwh *DesktopWindowHandler) Init(c *Container, e *bool) {
 	dwh.cont = c
 	dwh.exit = e
+	dwh.changed = make(chan struct{}, 1)
+
+	dwh.cont.SetLayout(&VBox{spacing: spacing()})
 
 	dwh.cont.AddItem("title", &Label{
-		position: Vector{0, 0},
-		size:     Vector{0, 0}, // will be resized later
-		text:     "Desktops",
-		textsize: 128,
-		valign:   CENTER,
-		halign:   CENTER,
-		color:    WHITE_COLOR,
-		bgcolor:  DEF_BG_COLOR,
-		bold:     false,
+		text:    "Desktops",
+		font:    TITLE_FONT,
+		valign:  CENTER,
+		halign:  CENTER,
+		color:   FOREGROUND,
+		bgcolor: BACKGROUND,
 	})
-	dwh.cont.ResizeItemToFraction("title", FractionVector{1.0, 0.1})
 
-	// add desktop images
-	for i := 1; i <= 6; i++ {
-		var img_surface *sdl.Surface
-		defer img_surface.Free()
+	// two columns of desktop tiles
+	dwh.cont.AddItemWithParams("desktops", &Container{
+		layout: &Grid{columns: 2, spacing: spacing()},
+	}, LayoutParams{flex: 1, halign: FILL, valign: FILL})
 
-		// get the surface
-		file, err := os.Open(DESKTOP_IMAGES_PATH + strconv.Itoa(i) + ".png")
-		if err != nil {
-			// assuming there was no image or image is corrupted; display empty space
-			img_surface, err = GetEmptyDesktop()
-			if err != nil {
-				panic(err)
-			}
-		} else {
-			img, _, err := image.Decode(file)
-			if err != nil {
-				img_surface, err = GetEmptyDesktop()
-				if err != nil {
-					panic(err)
-				}
-			} else {
-				img_surface, err = ImgTosurface(img)
-				if err != nil {
-					continue
-				}
-			}
+	dwh.refresh()
 
-		}
+	// keep the overview up to date while it is open
+	go dwh.listen()
+}
 
-		abs_pos := Vector{int32((i - 1) % 2), int32(math.Ceil(float64(i)/2.0)) - 1}
+// Fetches the workspaces from sway and rebuilds the desktop tiles
+func (dwh *DesktopWindowHandler) refresh() {
+	workspaces, err := getWorkspaces()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 
-		resized_surface, err := resizeSurface(img_surface, Vector{display_size.x / 12, display_size.y / 12})
-		if err != nil {
-			panic(err)
-		}
+	desktops := dwh.cont.GetItem("desktops").(*Container)
 
-		desktop_cont := &Container{
-			position: Vector{(display_size.x / 8) * abs_pos.x,
-				int32(float32(display_size.y)*0.1) + (int32((float32(display_size.y)*0.9)/6) * abs_pos.y)},
-			size: Vector{display_size.x / 8, int32(float32(display_size.y)*0.9) / 6},
-			items: map[string]Item{
-				"number": &Label{
-					position: Vector{0, 0},
-					size:     Vector{0, 0}, // will be resized
-					text:     strconv.Itoa(i),
-					textsize: 64,
-					valign:   TOP,
-					halign:   CENTER,
-					color:    WHITE_COLOR,
-					bgcolor:  DEF_BG_COLOR,
-					bold:     true,
-				},
-				"image": &Texture{
-					position: Vector{0, 0}, // will be repositioned
-					size:     Vector{0, 0}, // will be resized
-					texture:  resized_surface,
-				},
-			},
+	// remove the old tiles
+	for _, ws := range dwh.workspaces {
+		name := "desktop-" + ws.Name
+		if desktop_cont, ok := desktops.GetItem(name).(*Container); ok {
+			if tex, ok := desktop_cont.GetItem("image").(*Texture); ok {
+				tex.picture.Free()
+			}
 		}
+		desktops.RemoveItem(name)
+	}
 
-		// do resizing and repositioning of above mentioned items
-		desktop_cont.ResizeItemToFraction("number", FractionVector{0.2, 1})
+	dwh.workspaces = workspaces
 
-		desktop_cont.MoveItemToFraction("image", FractionVector{0.2, 0})
-		desktop_cont.ResizeItemToFraction("image", FractionVector{0.8, 1})
+	// the grid places them in the order of the workspaces
+	for _, ws := range dwh.workspaces {
+		desktop_cont, err := getDesktopCont(ws)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
 
-		// add the container to the parent container
-		dwh.cont.AddItem("desktop-"+strconv.Itoa(i), desktop_cont)
+		desktops.AddItem("desktop-"+ws.Name, desktop_cont)
 	}
 }
 
-// Gets you a surface the size of the current desktop, uniform colored
-func GetEmptyDesktop() (desktop *sdl.Surface, err error) {
-	desktop, err = sdl.CreateRGBSurface(0, display_size.x, display_size.y, 32, 0, 0, 0, 0)
+// Subscribes to workspace events and notifies the handler about them.
+// Runs in its own goroutine, so it must not touch any sdl state.
+func (dwh *DesktopWindowHandler) listen() {
+	events, err := ConnectIPC()
 	if err != nil {
-		return desktop, err
+		fmt.Println(err)
+		return
 	}
-	desktop.FillRect(nil, uint32(0x20272e))
-	return desktop, err
-}
+	defer events.Close()
 
-func (dwh *DesktopWindowHandler) Update() {
-	return
-}
-
-func (dwh *DesktopWindowHandler) HandleEvent(event sdl.Event) {
-	return
-}
-
-/*
-######################################################################################################
-######################################################################################################
-## Chapter: Desktop File Parser                                                                     ##
-######################################################################################################
-######################################################################################################
-*/
-
-var desktop_file_paths = []string{"/usr/local/share/applications/", "/usr/share/applications/", "~/.local/share/applications/"}
-
-func parseDesktopFile(file string) (entries map[string]string, err error) {
-	entries = make(map[string]string)
-
-	lines, err := readFile(file)
+	err = events.Subscribe("workspace", "output")
 	if err != nil {
-		return entries, err
+		fmt.Println(err)
+		return
 	}
 
-	for _, line := range lines {
-		matched, err := regexp.MatchString("([a-zA-Z]+)=(.*)", line)
+	for {
+		_, err := events.NextEvent()
 		if err != nil {
-			return entries, err
+			fmt.Println(err)
+			return
 		}
 
-		// if the line is a valid entry
-		if matched {
-
-			// Searches for a sequence of letters with length >= 1
-			key_regex, err := regexp.Compile("^[a-zA-Z]+")
-			if err != nil {
-				return entries, err
-			}
-
-			// Searches for a sequence of characters with = as a prefix
-			value_regex, err := regexp.Compile("=(.*)")
-			if err != nil {
-				return entries, err
-			}
-
-			// make entry but leave out the '='
-			entries[key_regex.FindString(line)] = value_regex.FindString(line)[1:]
+		// if a refresh is already pending, there is no need for another one
+		select {
+		case dwh.changed <- struct{}{}:
+			WakeWindow()
+		default:
+		}
+	}
+}
 
+// Gets the current workspaces from sway.
+// Without a sway connection, DESKTOP_COUNT numbered workspaces are assumed.
+func getWorkspaces() (workspaces []Workspace, err error) {
+	if sway_ipc == nil {
+		for i := 1; i <= DESKTOP_COUNT; i++ {
+			workspaces = append(workspaces, Workspace{Num: i, Name: strconv.Itoa(i)})
 		}
+		return workspaces, nil
 	}
 
-	return entries, nil
+	return sway_ipc.GetWorkspaces()
 }
 
-func readFile(filepath string) (lines []string, err error) {
-	file, err := os.Open(filepath)
-	if err != nil {
-		return lines, err
-	}
-	defer file.Close()
+// Gets a container showing the image, name and state of a workspace.
+func getDesktopCont(ws Workspace) (desktop_cont *Container, err error) {
+	image_size := Vector{display_size.x / 12, display_size.y / 12}
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	// get the image
+	var picture *Picture
+	file, err := os.Open(getDesktopImagePath(ws.Name))
+	if err != nil {
+		// assuming there was no image or image is corrupted; display empty space
+		picture = GetEmptyDesktop(image_size)
+	} else {
+		img, _, err := image.Decode(file)
+		file.Close()
+		if err != nil {
+			picture = GetEmptyDesktop(image_size)
+		} else {
+			picture = NewPicture(scaleImage(img, image_size))
+		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return lines, err
+	// the marker on the left shows the state of the workspace
+	marker_color := BACKGROUND
+	text_color := MUTED
+	switch {
+	case ws.Urgent:
+		marker_color = URGENT
+		text_color = FOREGROUND
+	case ws.Focused:
+		marker_color = ACCENT
+		text_color = FOREGROUND
+	case ws.Visible:
+		marker_color = MUTED
 	}
 
-	return lines, nil
-}
+	// marker, name and image next to each other
+	desktop_cont = &Container{layout: &HBox{spacing: spacing()}}
 
-func getFileHashes(dir_paths []string) (file_hashes map[string]string, err error) {
-	file_hashes = make(map[string]string)
+	desktop_cont.AddItemWithParams("marker", &Unicolor{
+		color: marker_color,
+	}, LayoutParams{min: Vector{MARKER_WIDTH, 0}, halign: FILL, valign: FILL})
 
-	for _, dir_path := range dir_paths {
+	desktop_cont.AddItemWithParams("number", &Label{
+		text:    ws.Name,
+		font:    SUBTITLE_FONT,
+		valign:  TOP,
+		halign:  CENTER,
+		color:   text_color,
+		bgcolor: BACKGROUND,
+	}, LayoutParams{halign: FILL, valign: TOP})
 
-		filepath.Walk(dir_path, func(path string, info os.FileInfo, err error) error {
+	desktop_cont.AddItemWithParams("image", &Texture{
+		picture: picture,
+	}, LayoutParams{halign: LEFT, valign: TOP})
 
-			// for some reason this function gets called sometimes with no fileinfo
-			// so we have to check for that
-			if info == nil {
-				return nil
-			}
+	return desktop_cont, nil
+}
 
-			// do not process folders
-			if info.IsDir() {
-				return nil
-			}
+// Gets you a picture of the given size, uniform colored
+func GetEmptyDesktop(size Vector) (desktop *Picture) {
+	return NewFilledPicture(size, SEPARATOR.RGB())
+}
 
-			file, err := os.Open(path)
-			if err != nil {
-				return err
-			}
+func (dwh *DesktopWindowHandler) Update() {
+	select {
+	case <-dwh.changed:
+		dwh.refresh()
+	default:
+	}
+}
 
-			// calculate the md5 hash of file
-			hash := md5.New()
-			if _, err := io.Copy(hash, file); err != nil {
-				return err
+func (dwh *DesktopWindowHandler) HandleEvent(event sdl.Event) {
+	switch ty := event.(type) {
+	case *sdl.TextInputEvent:
+		// typing the name (or number) of a workspace switches to it
+		text := GetInputText(ty)
+		for _, ws := range dwh.workspaces {
+			if text != ws.Name && text != strconv.Itoa(ws.Num) {
+				continue
 			}
 
-			// Close the file
-			err = file.Close()
+			err := RunSwayCommand("workspace " + swayQuote(ws.Name))
 			if err != nil {
-				return err
+				fmt.Println(err)
+				return
 			}
 
-			// assign the file path to the hash
-			file_hashes[hex.EncodeToString(hash.Sum(nil))] = path
-
-			return nil
-		})
-	}
-
-	return file_hashes, nil
-}
-
-// Gets all the files in a list of directories as keys in a map
-func getFiles(dir_paths []string) (file_paths map[string]struct{}, err error) {
-	file_paths = make(map[string]struct{})
-
-	for _, dir_path := range dir_paths {
-		filepath.Walk(dir_path, func(path string, info os.FileInfo, err error) error {
+			*dwh.exit = false
+			return
+		}
+	case *sdl.KeyboardEvent:
+		if ty.Type == sdl.KEYDOWN && ty.Keysym.Sym == sdl.K_RETURN {
+			*dwh.exit = false
+		}
+	case *sdl.MouseButtonEvent:
+		if ty.Type != sdl.MOUSEBUTTONDOWN || ty.Button != sdl.BUTTON_LEFT {
+			return
+		}
 
-			// sometimes this function gets called with no fileinfo
-			// so we have to ignore these calls
-			if info == nil {
-				return err
+		// clicking a tile switches to its workspace
+		desktops := dwh.cont.GetItem("desktops").(*Container)
+		name, _, _ := desktops.ChildAt(desktops.FromWindow(Vector{ty.X, ty.Y}))
+		for _, ws := range dwh.workspaces {
+			if name != "desktop-"+ws.Name {
+				continue
 			}
 
-			// also ignore folders
-			if info.IsDir() {
-				return err
+			err := RunSwayCommand("workspace " + swayQuote(ws.Name))
+			if err != nil {
+				fmt.Println(err)
+				return
 			}
 
-			// assign an empty struct to the key
-			file_paths[path] = struct{}{}
-
-			return err
-		})
-	}
-
-	return file_paths, err
-}
-
-func checkEntryMatch(key string, value string, parsedfile map[string]string) (matches bool) {
-	val, ok := parsedfile[key]
-
-	if ok {
-		// Check match ignoring case
-		if strings.EqualFold(val, value) {
-			return true
+			*dwh.exit = false
+			return
 		}
 	}
-
-	return false
 }
 
-func launchDesktopFile(path string) (err error) {
-	return exec.Command("gtk-launch", path).Run()
-}
+/*
+######################################################################################################
+######################################################################################################
+## Chapter: Desktop File Parser                                                                     ##
+######################################################################################################
+######################################################################################################
+*/
+
+// The directories containing desktop files, the ones first in the list take precedence
+var desktop_file_paths = getDesktopFileDirs()
 
 /*
 ######################################################################################################
@@ -1041,152 +1734,237 @@ func launchDesktopFile(path string) (err error) {
 
 // gets the path to the data file
 func getDataFilePath() (path string, err error) {
-	// The value regexp
-	value_regex, err := regexp.Compile("=(.*)")
+	state_dir, err := getStateDir()
 	if err != nil {
 		return "", err
 	}
+	path = filepath.Join(state_dir, "data.csv")
 
-	// the variable regexp
-	var_regex, err := regexp.Compile("HOME=(.*)")
+	// the data file used to be in $HOME/sway/sidebar/, move it over once
+	home, err := getHomePath()
 	if err != nil {
-		return "", err
+		return path, nil
 	}
+	legacy_path := filepath.Join(home, "sway", "sidebar", "data.csv")
 
-	for _, line := range os.Environ() {
-		// if its the entry we search for ($HOME)
-		if var_regex.MatchString(line) {
-			// return the value of the env variable + our config file location
-			return value_regex.FindString(line)[1:] + "/sway/sidebar/data.csv", err
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := os.Stat(legacy_path); err == nil {
+			err = os.MkdirAll(state_dir, 0755)
+			if err == nil {
+				err = os.Rename(legacy_path, path)
+			}
+			if err != nil {
+				fmt.Println("could not move", legacy_path, "to", path+":", err)
+				return legacy_path, nil
+			}
 		}
 	}
 
-	return "", errors.New("Enviroment variable $HOME not found")
+	return path, nil
 }
 
 func getHomePath() (path string, err error) {
-	// The value regexp
-	value_regex, err := regexp.Compile("=(.*)")
+	// only $HOME itself (matching "HOME=" would also find e.g. $CARGO_HOME)
+	path = os.Getenv("HOME")
+	if path == "" {
+		return "", errors.New("Enviroment variable $HOME not found")
+	}
+
+	return path, nil
+}
+
+// The first record of the data file holds the version of its format.
+// Files without it are from before versioning (version 0, same layout as version 1).
+// Version 2 added the launch times after the count (see LaunchHistory).
+const (
+	DATA_FILE_HEADER  = "#sidebar-data"
+	DATA_FILE_VERSION = 2
+)
+
+// Locks the data file for all sidebar instances (syscall.LOCK_SH or syscall.LOCK_EX).
+// A separate lock file is used, because the data file itself is replaced on every write.
+func lockDataFile(path string, how int) (unlock func(), err error) {
+	err = os.MkdirAll(filepath.Dir(path), 0755)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// the variable regexp
-	var_regex, err := regexp.Compile("HOME=(.*)")
+	file, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0666)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	for _, line := range os.Environ() {
-		// if its the entry we search for ($HOME)
-		if var_regex.MatchString(line) {
-			// return the value of the env variable + our config file location
-			return value_regex.FindString(line)[1:], err
-		}
+	err = syscall.Flock(int(file.Fd()), how)
+	if err != nil {
+		file.Close()
+		return nil, err
 	}
 
-	return "", errors.New("Enviroment variable $HOME not found")
+	return func() {
+		syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+	}, nil
 }
 
-// Reads out the data csv saved under CONFIG_FILE_PATH
+// Reads out the data csv saved under path
 func readDataFile(path string) (data map[string][]string, err error) {
-	data = make(map[string][]string)
-
-	// open or create file in readonly mode
-	file, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, 0666)
+	unlock, err := lockDataFile(path, syscall.LOCK_SH)
 	if err != nil {
-		return data, err
+		return make(map[string][]string), err
 	}
+	defer unlock()
 
-	reader := csv.NewReader(file)
+	return loadDataFile(path)
+}
 
-	raw_data, err := reader.ReadAll()
+// Reads, changes and writes the data file without another instance getting in between
+func updateDataFile(path string, update func(data map[string][]string) error) (err error) {
+	unlock, err := lockDataFile(path, syscall.LOCK_EX)
 	if err != nil {
-		return data, err
+		return err
 	}
+	defer unlock()
 
-	// make the first entry of each line the line's key
-	for _, line := range raw_data {
-		data[line[0]] = line[1:]
+	data, err := loadDataFile(path)
+	if err != nil {
+		return err
+	}
+
+	err = update(data)
+	if err != nil {
+		return err
 	}
 
-	return data, err
+	return saveDataFile(path, data)
 }
 
-// overwrites the current datafile by replacing it
-func writeDataFile(path string, data map[string][]string) (err error) {
-	// delete the old data file
-	deleteDataFile(path)
+// Parses the data file (the caller has to hold the lock).
+// Broken rows are skipped with a warning instead of failing the whole file.
+func loadDataFile(path string) (data map[string][]string, err error) {
+	data = make(map[string][]string)
 
-	// open or create file in writeonly mode
-	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0666)
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return data, nil
+	}
 	if err != nil {
-		return err
+		return data, err
 	}
+	defer file.Close()
+
+	// every row is parsed on its own, so a broken row (e.g. an open quote) can not take the following ones with it
+	scanner := bufio.NewScanner(file)
+
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
 
-	writer := csv.NewWriter(file)
+		reader := csv.NewReader(strings.NewReader(scanner.Text()))
+		reader.FieldsPerRecord = -1 // rows are checked below
 
-	var records [][]string
+		record, err := reader.Read()
+		if err != nil {
+			fmt.Printf("warning: skipping broken row in %s:%d: %v\n", path, line, err)
+			continue
+		}
+
+		if line == 1 && record[0] == DATA_FILE_HEADER {
+			version := 0
+			if len(record) > 1 {
+				version, err = strconv.Atoi(record[1])
+			}
+			if err != nil || version < 1 {
+				fmt.Println("warning: invalid version in", path+", reading it as version", DATA_FILE_VERSION)
+			}
+
+			// never touch (and possibly lose) data of a newer format
+			if version > DATA_FILE_VERSION {
+				return data, fmt.Errorf("%s has version %d, but only version %d is supported", path, version, DATA_FILE_VERSION)
+			}
+			continue
+		}
+
+		if len(record) < 2 || record[0] == "" {
+			fmt.Printf("warning: skipping incomplete row in %s:%d\n", path, line)
+			continue
+		}
+
+		if _, err := strconv.Atoi(record[1]); err != nil {
+			fmt.Printf("warning: skipping row with invalid count in %s:%d\n", path, line)
+			continue
+		}
+
+		if _, ok := data[record[0]]; ok {
+			fmt.Printf("warning: skipping duplicate row in %s:%d\n", path, line)
+			continue
+		}
 
-	// reformat data to two dimensional array
-	for key, val := range data {
-		records = append(records, append([]string{key}, val...))
+		// additional columns are kept as they are
+		data[record[0]] = record[1:]
 	}
 
-	return writer.WriteAll(records)
+	return data, scanner.Err()
 }
 
-// increments the use counter of a entry in the data file
-func incrementDataFileEntry(path string, key string) (err error) {
-	// get the data in a validated form
-	data, err := getValidatedData(path)
+// Writes the data file (the caller has to hold the exclusive lock).
+// The data is written into a temporary file that then replaces the old one,
+// so the file is either completely old or completely new, even after a crash.
+func saveDataFile(path string, data map[string][]string) (err error) {
+	dir := filepath.Dir(path)
+
+	err = os.MkdirAll(dir, 0755)
 	if err != nil {
 		return err
 	}
 
-	i, err := strconv.Atoi(data[key][0])
+	file, err := os.CreateTemp(dir, ".data-*.csv")
 	if err != nil {
 		return err
 	}
+	defer os.Remove(file.Name())
 
-	data[key] = []string{strconv.Itoa(i + 1)}
+	records := [][]string{{DATA_FILE_HEADER, strconv.Itoa(DATA_FILE_VERSION)}}
 
-	return writeDataFile(path, data)
-}
+	// reformat data to two dimensional array (sorted, so the file is easy to diff)
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
 
-// removes the current data file.
-func deleteDataFile(path string) (err error) {
-	return os.Remove(path)
-}
+	for _, key := range keys {
+		records = append(records, append([]string{key}, data[key]...))
+	}
 
-// Same as readDataFile(), but validates entries first
-func getValidatedData(path string) (data map[string][]string, err error) {
-	data, err = readDataFile(path)
+	err = csv.NewWriter(file).WriteAll(records)
 	if err != nil {
-		return data, err
+		file.Close()
+		return err
 	}
 
-	files, err := getFiles(desktop_file_paths)
+	err = file.Sync()
 	if err != nil {
-		return data, err
+		file.Close()
+		return err
 	}
 
-	// add new desktop files
-	for path, _ := range files {
-		// if the desktop file is missing in data
-		if _, ok := data[path]; !ok {
-			data[path] = []string{"0"}
-		}
+	err = file.Close()
+	if err != nil {
+		return err
 	}
 
-	// remove no longer existent desktop files
-	for path, _ := range data {
-		// if there is no such file
-		if _, ok := files[path]; !ok {
-			delete(data, path)
-		}
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		return err
+	}
+
+	// make the rename itself durable
+	dir_file, err := os.Open(dir)
+	if err != nil {
+		return err
 	}
+	defer dir_file.Close()
 
-	// return validated data variable
-	return data, err
+	return dir_file.Sync()
 }
@@ -0,0 +1,626 @@
+package main
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestContainerDrawsItemsWithoutSize(t *testing.T) {
+	cont := &Container{size: Vector{20, 10}, layout: &HBox{}}
+
+	// the layout gives the first item no width, the second one takes all of it
+	empty := &Unicolor{color: URGENT, dirty: true}
+	filled := &Unicolor{color: ACCENT}
+	cont.AddItem("empty", empty)
+	cont.AddItemWithParams("filled", filled, LayoutParams{flex: 1, halign: FILL, valign: FILL})
+
+	img := image.NewRGBA(image.Rect(0, 0, 20, 10))
+	err := cont.Draw(NewImageCanvas(img))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if empty.GetSize().x != 0 {
+		t.Fatalf("the empty item got a width of %d", empty.GetSize().x)
+	}
+	if cont.Dirty() {
+		t.Error("the container is still dirty after drawing an item without a size")
+	}
+
+	// nothing of the empty item is visible
+	want := rgbToColor(ACCENT.RGB())
+	for x := 0; x < 20; x++ {
+		if got := img.RGBAAt(x, 5); got != want {
+			t.Fatalf("pixel %d is %v, want %v", x, got, want)
+		}
+	}
+}
+
+func TestContainerOrder(t *testing.T) {
+	cont := &Container{}
+	for _, name := range []string{"a", "b", "c"} {
+		cont.AddItem(name, &Unicolor{})
+	}
+
+	steps := []struct {
+		change func()
+		want   string
+	}{
+		// added items go last, inserted ones where they are inserted
+		{func() {}, "a b c"},
+		{func() { cont.InsertItem("d", &Unicolor{}, 1) }, "a d b c"},
+		{func() { cont.InsertItem("e", &Unicolor{}, -5) }, "e a d b c"},
+		{func() { cont.InsertItem("f", &Unicolor{}, 99) }, "e a d b c f"},
+		{func() { cont.InsertItemBefore("g", &Unicolor{}, "d") }, "e a g d b c f"},
+		{func() { cont.InsertItemAfter("h", &Unicolor{}, "e") }, "e h a g d b c f"},
+		{func() { cont.InsertItemBefore("i", &Unicolor{}, "missing") }, "e h a g d b c f i"},
+		{func() { cont.InsertItemAfter("j", &Unicolor{}, "missing") }, "e h a g d b c f i j"},
+
+		// replaced items keep their place, even when inserted somewhere else
+		{func() { cont.AddItem("a", &Unicolor{}) }, "e h a g d b c f i j"},
+		{func() { cont.InsertItem("e", &Unicolor{}, 5) }, "e h a g d b c f i j"},
+
+		{func() { cont.RemoveItem("h") }, "e a g d b c f i j"},
+		{func() { cont.RemoveItem("missing") }, "e a g d b c f i j"},
+		{func() { cont.ReorderItem("e", 3) }, "a g d e b c f i j"},
+		{func() { cont.ReorderItem("j", -1) }, "j a g d e b c f i"},
+		{func() { cont.ReorderItem("missing", 0) }, "j a g d e b c f i"},
+		{func() { cont.MoveItemBefore("i", "a") }, "j i a g d e b c f"},
+		{func() { cont.MoveItemBefore("j", "d") }, "i a g j d e b c f"},
+		{func() { cont.MoveItemAfter("i", "b") }, "a g j d e b i c f"},
+		{func() { cont.MoveItemAfter("f", "g") }, "a g f j d e b i c"},
+		{func() { cont.MoveItemAfter("a", "a") }, "a g f j d e b i c"},
+		{func() { cont.MoveItemBefore("a", "missing") }, "a g f j d e b i c"},
+	}
+
+	for i, step := range steps {
+		step.change()
+		if got := strings.Join(cont.ItemNames(), " "); got != step.want {
+			t.Fatalf("step %d: the order is %q, want %q", i, got, step.want)
+		}
+	}
+
+	// every item is found by its name and visited in the order
+	var visited []string
+	cont.ForEachItem(func(name string, item Item) {
+		if cont.GetItem(name) != item || cont.ItemNames()[cont.IndexOf(name)] != name {
+			t.Errorf("%s is not found by its name", name)
+		}
+		visited = append(visited, name)
+	})
+	if !reflect.DeepEqual(visited, cont.ItemNames()) || cont.Len() != len(visited) {
+		t.Errorf("visited %q of %d items", visited, cont.Len())
+	}
+	if cont.GetItem("h") != nil || cont.IndexOf("h") != -1 {
+		t.Error("a removed item is still found")
+	}
+}
+
+func TestContainerParents(t *testing.T) {
+	cont := &Container{}
+	first, second := &Container{}, &Container{}
+
+	cont.AddItem("sub", first)
+	if first.parent != cont {
+		t.Fatal("an added container does not know its parent")
+	}
+
+	// a replaced container forgets its parent, so does a removed one
+	cont.AddItem("sub", second)
+	if first.parent != nil || second.parent != cont {
+		t.Error("a replaced container still knows its parent")
+	}
+	cont.RemoveItem("sub")
+	if second.parent != nil {
+		t.Error("a removed container still knows its parent")
+	}
+}
+
+// The names of the children of a container in the order they are drawn in
+func drawOrderNames(cont *Container) (names []string) {
+	for _, child := range cont.drawOrder() {
+		names = append(names, child.name)
+	}
+	return names
+}
+
+func TestContainerZIndex(t *testing.T) {
+	useTestTheme(t)
+
+	// a and b overlap from 10 to 20, a and c from 5 to 10 (b starts at 10)
+	cont := &Container{size: Vector{30, 10}}
+	cont.AddItem("a", &Unicolor{position: Vector{0, 0}, size: Vector{20, 10}, color: ACCENT})
+	cont.AddItem("b", &Unicolor{position: Vector{10, 0}, size: Vector{20, 10}, color: URGENT})
+	cont.AddItem("c", &Unicolor{position: Vector{5, 0}, size: Vector{5, 10}, color: SELECTION})
+
+	steps := []struct {
+		change func()
+		order  string
+		// the colors at x = 7 and x = 15
+		left  ColorRole
+		right ColorRole
+	}{
+		// without z-indexes, in the order
+		{func() {}, "a b c", SELECTION, URGENT},
+
+		// raised above the others
+		{func() { cont.SetZIndex("a", 1) }, "b c a", ACCENT, ACCENT},
+
+		// lowered below the others, equal z-indexes in the order
+		{func() { cont.SetZIndex("a", -1); cont.SetZIndex("c", -1) }, "a c b", SELECTION, URGENT},
+		{func() { cont.MoveItemBefore("c", "a") }, "c a b", ACCENT, URGENT},
+
+		// back to the order
+		{func() { cont.SetZIndex("a", 0); cont.SetZIndex("c", 0); cont.SetZIndex("missing", 5) }, "c a b", ACCENT, URGENT},
+	}
+
+	for i, step := range steps {
+		step.change()
+		if got := strings.Join(drawOrderNames(cont), " "); got != step.order {
+			t.Errorf("step %d: drawn in the order %q, want %q", i, got, step.order)
+		}
+		if got := strings.Join(cont.ItemNames(), " "); i < 3 && got != "a b c" {
+			t.Errorf("step %d: the z-index changed the order to %q", i, got)
+		}
+
+		img := image.NewRGBA(image.Rect(0, 0, 30, 10))
+		if err := cont.Draw(NewImageCanvas(img)); err != nil {
+			t.Fatal(err)
+		}
+		if !hasColor(img, 7, 5, step.left.RGB()) || !hasColor(img, 15, 5, step.right.RGB()) {
+			t.Errorf("step %d: drew %v and %v on top", i, img.RGBAAt(7, 5), img.RGBAAt(15, 5))
+		}
+	}
+}
+
+// A window with containers in containers:
+// outer at (10, 20) in the window, inner at (5, 5) in outer, leaf at (2, 3) in inner
+// and wide at (15, 0) in inner, which is cut off at the edge of inner
+func newNestedTestContainers() (root *Container, outer *Container, inner *Container) {
+	root = &Container{size: Vector{100, 100}}
+	outer = &Container{position: Vector{10, 20}, size: Vector{50, 50}}
+	inner = &Container{position: Vector{5, 5}, size: Vector{20, 20}}
+
+	inner.AddItem("leaf", &Unicolor{position: Vector{2, 3}, size: Vector{10, 10}, color: ACCENT})
+	inner.AddItem("wide", &Unicolor{position: Vector{15, 0}, size: Vector{30, 5}, color: URGENT})
+	outer.AddItem("inner", inner)
+	root.AddItem("outer", outer)
+
+	return root, outer, inner
+}
+
+func TestContainerCoordinates(t *testing.T) {
+	root, outer, inner := newNestedTestContainers()
+
+	tests := []struct {
+		cont  *Container
+		local Vector
+		// in the window
+		window Vector
+	}{
+		{root, Vector{3, 4}, Vector{3, 4}},
+		{outer, Vector{0, 0}, Vector{10, 20}},
+		{inner, Vector{0, 0}, Vector{15, 25}},
+		{inner, Vector{1, 1}, Vector{16, 26}},
+		{inner, Vector{-15, -25}, Vector{0, 0}},
+	}
+
+	for _, test := range tests {
+		if got := test.cont.ToWindow(test.local); got != test.window {
+			t.Errorf("%v of a container at %v is %v in the window, want %v", test.local, test.cont.position, got, test.window)
+		}
+		if got := test.cont.FromWindow(test.window); got != test.local {
+			t.Errorf("%v of the window is %v in a container at %v, want %v", test.window, got, test.cont.position, test.local)
+		}
+	}
+
+	if got := inner.ItemToWindow("leaf", Vector{1, 1}); got != (Vector{18, 29}) {
+		t.Errorf("(1, 1) of the leaf is %v in the window", got)
+	}
+	if got := inner.ItemToWindow("missing", Vector{1, 1}); got != (Vector{16, 26}) {
+		t.Errorf("(1, 1) of a missing item is %v in the window", got)
+	}
+
+	// a container that is not in the window any more is on its own
+	outer.RemoveItem("inner")
+	if got := inner.ToWindow(Vector{0, 0}); got != (Vector{5, 5}) {
+		t.Errorf("(0, 0) of a removed container is %v", got)
+	}
+}
+
+func TestContainerHitTesting(t *testing.T) {
+	root, outer, inner := newNestedTestContainers()
+
+	// a point of the window, the item of root there and the innermost item there (with the point relative to them)
+	tests := []struct {
+		point       Vector
+		name        string
+		item        Item
+		local       Vector
+		child_local Vector
+	}{
+		{Vector{17, 28}, "outer", inner.GetItem("leaf"), Vector{0, 0}, Vector{7, 8}},
+		{Vector{26, 37}, "outer", inner.GetItem("leaf"), Vector{9, 9}, Vector{16, 17}},
+		{Vector{32, 26}, "outer", inner.GetItem("wide"), Vector{2, 1}, Vector{22, 6}},
+
+		// inner, but none of its items
+		{Vector{27, 38}, "outer", inner, Vector{12, 13}, Vector{17, 18}},
+
+		// wide is cut off at the edge of inner
+		{Vector{37, 26}, "outer", outer, Vector{27, 6}, Vector{27, 6}},
+
+		// outside of everything
+		{Vector{80, 80}, "", nil, Vector{80, 80}, Vector{80, 80}},
+		{Vector{100, 5}, "", nil, Vector{100, 5}, Vector{100, 5}},
+		{Vector{-1, 5}, "", nil, Vector{-1, 5}, Vector{-1, 5}},
+	}
+
+	for _, test := range tests {
+		name, _, local := root.ChildAt(test.point)
+		if name != test.name || local != test.child_local {
+			t.Errorf("%v: found %q at %v, want %q at %v", test.point, name, local, test.name, test.child_local)
+		}
+
+		item, local := root.ItemAt(test.point)
+		if item != test.item || local != test.local {
+			t.Errorf("%v: found %T at %v, want %T at %v", test.point, item, local, test.item, test.local)
+		}
+
+		// the point is where the item is in the window
+		if cont, ok := item.(*Container); ok && cont.FromWindow(test.point) != local {
+			t.Errorf("%v: the container found has it at %v", test.point, cont.FromWindow(test.point))
+		}
+	}
+
+	// overlapping items: the one drawn last
+	inner.AddItem("cover", &Unicolor{position: Vector{0, 0}, size: Vector{20, 20}})
+	if item, _ := root.ItemAt(Vector{17, 28}); item != inner.GetItem("cover") {
+		t.Errorf("found %T under the covering item", item)
+	}
+	inner.SetZIndex("leaf", 1)
+	if item, _ := root.ItemAt(Vector{17, 28}); item != inner.GetItem("leaf") {
+		t.Errorf("found %T instead of the raised item", item)
+	}
+}
+
+func TestContainerClipsNested(t *testing.T) {
+	useTestTheme(t)
+
+	root, _, _ := newNestedTestContainers()
+
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	canvas := NewImageCanvas(img)
+	canvas.FillRect(Vector{0, 0}, root.size, background_color)
+	if err := root.Draw(canvas); err != nil {
+		t.Fatal(err)
+	}
+
+	// the items are drawn at their place in the window, and only inside of their containers
+	want := image.Rect(17, 25, 35, 38)
+	if bounds := drawnBounds(img, BACKGROUND.RGB()); bounds != want {
+		t.Errorf("drew at %v, want %v", bounds, want)
+	}
+	if !hasColor(img, 17, 28, ACCENT.RGB()) || !hasColor(img, 26, 37, ACCENT.RGB()) || hasColor(img, 27, 38, ACCENT.RGB()) {
+		t.Error("the leaf is not drawn where it is")
+	}
+	if !hasColor(img, 30, 25, URGENT.RGB()) || !hasColor(img, 34, 29, URGENT.RGB()) || hasColor(img, 35, 25, URGENT.RGB()) {
+		t.Error("the wide item is not cut off at the edge of its container")
+	}
+}
+
+func TestSwayQuote(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"2", `"2"`},
+		{"2: web", `"2: web"`},
+		{"café", `"café"`},
+		{"a\tb", "\"a\tb\""},
+		{`say "hi"`, `"say \"hi\""`},
+		{`back\slash`, `"back\\slash"`},
+	}
+
+	for _, test := range tests {
+		if got := swayQuote(test.arg); got != test.want {
+			t.Errorf("swayQuote(%q) = %s, want %s", test.arg, got, test.want)
+		}
+	}
+}
+
+// Draws an item onto the background, like a container does
+func drawTestItem(t *testing.T, item Item, size Vector) (img *image.RGBA) {
+	t.Helper()
+
+	item.SetSize(size)
+	img = image.NewRGBA(image.Rect(0, 0, int(size.x), int(size.y)))
+
+	canvas := NewImageCanvas(img)
+	canvas.FillRect(Vector{0, 0}, size, background_color)
+	err := item.Draw(canvas)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if item.Dirty() {
+		t.Error("the item is still dirty after drawing")
+	}
+	return img
+}
+
+func TestLabelDraw(t *testing.T) {
+	useTestTheme(t)
+
+	tests := []struct {
+		halign Align
+		valign Align
+	}{
+		{LEFT, TOP},
+		{CENTER, CENTER},
+		{RIGHT, BOTTOM},
+	}
+
+	for _, test := range tests {
+		label := &Label{text: "Power", font: HEADER_FONT, halign: test.halign, valign: test.valign, color: FOREGROUND, bgcolor: SEPARATOR}
+		measured := label.Measure()
+		size := Vector{200, 40}
+		img := drawTestItem(t, label, size)
+
+		var x, y int32
+		switch test.halign {
+		case CENTER:
+			x = (size.x - measured.x) / 2
+		case RIGHT:
+			x = size.x - measured.x
+		}
+		switch test.valign {
+		case CENTER:
+			y = (size.y - measured.y) / 2
+		case BOTTOM:
+			y = size.y - measured.y
+		}
+		text_rect := image.Rect(int(x), int(y), int(x+measured.x), int(y+measured.y))
+
+		// the label fills exactly the measured rectangle with its background
+		if drawn := drawnBounds(img, BACKGROUND.RGB()); drawn != text_rect {
+			t.Errorf("align %d/%d: drew %v, measured %v", test.halign, test.valign, drawn, text_rect)
+		}
+		if !hasColor(img, text_rect.Min.X, text_rect.Min.Y, SEPARATOR.RGB()) {
+			t.Errorf("align %d/%d: the text has no background", test.halign, test.valign)
+		}
+	}
+}
+
+func TestLabelHighlights(t *testing.T) {
+	useTestTheme(t)
+
+	label := &Label{text: "llll", font: HEADER_FONT, color: FOREGROUND, bgcolor: BACKGROUND, highlights: []int{0, 1}, hlcolor: ACCENT}
+	measured := label.Measure()
+	img := drawTestItem(t, label, measured)
+
+	// the first half is highlighted, the second is not
+	count := func(rgb uint32, from int, to int) (n int) {
+		for y := 0; y < int(measured.y); y++ {
+			for x := from; x < to; x++ {
+				if hasColor(img, x, y, rgb) {
+					n++
+				}
+			}
+		}
+		return n
+	}
+
+	half := int(measured.x) / 2
+	if count(ACCENT.RGB(), 0, half) == 0 || count(FOREGROUND.RGB(), 0, half) != 0 {
+		t.Error("the highlighted characters are not drawn in the highlight color")
+	}
+	if count(FOREGROUND.RGB(), half, int(measured.x)) == 0 || count(ACCENT.RGB(), half, int(measured.x)) != 0 {
+		t.Error("the other characters are not drawn in the text color")
+	}
+}
+
+func TestLabelWithoutText(t *testing.T) {
+	useTestTheme(t)
+
+	label := &Label{font: HEADER_FONT, color: FOREGROUND, bgcolor: SEPARATOR}
+	if measured := label.Measure(); measured.x != 0 || measured.y == 0 {
+		t.Errorf("an empty label measured %v, want one empty line", measured)
+	}
+
+	img := drawTestItem(t, label, Vector{50, 20})
+	if drawn := drawnBounds(img, BACKGROUND.RGB()); !drawn.Empty() {
+		t.Errorf("an empty label drew %v", drawn)
+	}
+}
+
+func TestGetDataFilePath(t *testing.T) {
+	home := t.TempDir()
+	state := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_STATE_HOME", state)
+
+	want := filepath.Join(state, SIDEBAR_SUBDIR, "data.csv")
+
+	path, err := getDataFilePath()
+	if err != nil || path != want {
+		t.Fatalf("got %q, %v, want %q", path, err, want)
+	}
+
+	// the file of older versions is moved over
+	legacy := filepath.Join(home, "sway", "sidebar", "data.csv")
+	writeTestFiles(t, home, map[string]string{"sway/sidebar/data.csv": "/a.desktop,1\n"})
+
+	path, err = getDataFilePath()
+	if err != nil || path != want {
+		t.Fatalf("got %q, %v with an old file, want %q", path, err, want)
+	}
+	if data, err := os.ReadFile(want); err != nil || string(data) != "/a.desktop,1\n" {
+		t.Errorf("the moved file contains %q, %v", data, err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("the old file is still there: %v", err)
+	}
+
+	// but it never replaces the new one
+	writeTestFiles(t, home, map[string]string{"sway/sidebar/data.csv": "/old.desktop,1\n"})
+	if _, err := getDataFilePath(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(want); string(data) != "/a.desktop,1\n" {
+		t.Errorf("the old file replaced the new one: %q", data)
+	}
+}
+
+// Writes a data file with the given contents and loads it
+func loadTestDataFile(t *testing.T, content string) (data map[string][]string, err error) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return loadDataFile(path)
+}
+
+func TestLoadDataFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string][]string
+	}{
+		{"empty", "", map[string][]string{}},
+		{"current version", "#sidebar-data,2\n/a.desktop,3,100;200\n/b.desktop,1\n",
+			map[string][]string{"/a.desktop": {"3", "100;200"}, "/b.desktop": {"1"}}},
+
+		// files from before versioning have the same layout as version 1
+		{"without version", "/a.desktop,3\n/b.desktop,1\n",
+			map[string][]string{"/a.desktop": {"3"}, "/b.desktop": {"1"}}},
+		{"version 1", "#sidebar-data,1\n/a.desktop,3\n",
+			map[string][]string{"/a.desktop": {"3"}}},
+		{"invalid version", "#sidebar-data,x\n/a.desktop,3\n",
+			map[string][]string{"/a.desktop": {"3"}}},
+
+		// broken rows do not take the others with them
+		{"broken rows", strings.Join([]string{
+			"#sidebar-data,2",
+			`"/open quote.desktop,1`,
+			"/a.desktop,3",
+			"",
+			"/no-count.desktop",
+			",4",
+			"/bad-count.desktop,many",
+			"/a.desktop,5",
+			`"/quoted, with comma.desktop",2`,
+		}, "\n"), map[string][]string{"/a.desktop": {"3"}, "/quoted, with comma.desktop": {"2"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := loadTestDataFile(t, test.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(data, test.want) {
+				t.Errorf("got %q, want %q", data, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadDataFileOfNewerVersion(t *testing.T) {
+	// data of a newer format must not be overwritten with what this version understands of it
+	_, err := loadTestDataFile(t, "#sidebar-data,"+strconv.Itoa(DATA_FILE_VERSION+1)+"\n/a.desktop,3,x,y\n")
+	if err == nil {
+		t.Error("a file of a newer version was loaded")
+	}
+}
+
+func TestLoadMissingDataFile(t *testing.T) {
+	data, err := loadDataFile(filepath.Join(t.TempDir(), "data.csv"))
+	if err != nil || len(data) != 0 {
+		t.Errorf("got %q, %v for a missing file", data, err)
+	}
+
+	err = updateDataFile(filepath.Join(t.TempDir(), "state", "data.csv"), func(data map[string][]string) error { return nil })
+	if err != nil {
+		t.Errorf("updating a missing file: %v", err)
+	}
+}
+
+func TestSaveDataFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "state")
+	path := filepath.Join(dir, "data.csv")
+
+	data := map[string][]string{
+		"/b.desktop":           {"1", "100"},
+		"/a.desktop":           {"3", "100;200;300"},
+		"/with, comma.desktop": {"2"},
+	}
+	if err := saveDataFile(path, data); err != nil {
+		t.Fatal(err)
+	}
+
+	// with a version and sorted
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "#sidebar-data,2\n/a.desktop,3,100;200;300\n/b.desktop,1,100\n\"/with, comma.desktop\",2\n"
+	if string(content) != want {
+		t.Errorf("the file contains\n%s\nwant\n%s", content, want)
+	}
+
+	// nothing is left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("the directory contains %d files, want only the data file", len(entries))
+	}
+
+	loaded, err := loadDataFile(path)
+	if err != nil || !reflect.DeepEqual(loaded, data) {
+		t.Errorf("loaded %q, %v, want %q", loaded, err, data)
+	}
+}
+
+func TestUpdateDataFileLocks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.csv")
+
+	// every update reads what the one before wrote, none of them gets lost
+	var wait sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+
+			err := updateDataFile(path, func(data map[string][]string) error {
+				count := 0
+				if row, ok := data["/a.desktop"]; ok {
+					count, _ = strconv.Atoi(row[0])
+				}
+				data["/a.desktop"] = []string{strconv.Itoa(count + 1)}
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wait.Wait()
+
+	data, err := readDataFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := data["/a.desktop"]; len(got) != 1 || got[0] != "20" {
+		t.Errorf("the count is %q after 20 updates", got)
+	}
+}
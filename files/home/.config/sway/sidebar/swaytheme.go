@@ -0,0 +1,379 @@
+package main
+
+/*
+##############################################################
+# Section: Imports
+##############################################################
+*/
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+######################################################################################################
+######################################################################################################
+## Chapter: Sway Theme																				##
+######################################################################################################
+######################################################################################################
+*/
+
+/*
+##############################################################
+# Section: Constants & Fields
+##############################################################
+*/
+
+// The name of the theme built from the sway config
+const SWAY_THEME = "sway"
+
+// The colors of a client.* class: border, background, text, indicator, child_border
+type SwayClientColors [5]uint32
+
+// What the sidebar needs from the sway config
+type SwayConfig struct {
+	// by class (focused, focused_inactive, unfocused, urgent, placeholder)
+	Clients map[string]SwayClientColors
+
+	// the font directive, e.g. "pango:DejaVu Sans Mono 10" (empty if not set)
+	Font string
+
+	// gaps inner, in pixels (0 if not set)
+	Gaps int32
+}
+
+// Reads a sway config and the files it includes, in the order sway does
+type swayConfigReader struct {
+	config SwayConfig
+
+	// set with set $name value, in a file or any file read before it
+	variables map[string]string
+
+	// every file is only read once
+	included map[string]bool
+}
+
+// Words at the end of pango font descriptions that are not part of the family
+var pango_style_words = map[string]bool{
+	"normal": true, "roman": true, "oblique": true, "italic": true,
+	"small-caps": true, "thin": true, "ultra-light": true, "light": true,
+	"semi-light": true, "book": true, "regular": true, "medium": true,
+	"semi-bold": true, "bold": true, "ultra-bold": true, "heavy": true,
+	"ultra-heavy": true, "condensed": true, "semi-condensed": true, "expanded": true,
+}
+
+/*
+##############################################################
+# Section: Theme
+##############################################################
+*/
+
+// Builds a theme from the client colors, font and inner gaps of the sway config.
+// Everything the config does not set stays as in the default theme.
+func loadSwayTheme() (theme Theme, err error) {
+	theme = builtin_themes[DEFAULT_THEME]
+	theme.Name = SWAY_THEME
+
+	config, err := loadSwayConfig()
+	if err != nil {
+		return theme, err
+	}
+
+	// which part of which client class becomes which role
+	roles := []struct {
+		role  ColorRole
+		class string
+		index int
+	}{
+		{BACKGROUND, "unfocused", 1},
+		{FOREGROUND, "focused", 2},
+		{MUTED, "unfocused", 2},
+		{SEPARATOR, "focused_inactive", 1},
+		{ACCENT, "focused", 3},
+		{SELECTION, "focused", 1},
+		{URGENT, "urgent", 1},
+	}
+
+	for _, role := range roles {
+		if colors, ok := config.Clients[role.class]; ok {
+			theme.Colors[role.role] = colors[role.index]
+		}
+	}
+
+	if config.Font != "" {
+		family, style, _ := parsePangoFont(config.Font)
+		if style == "" {
+			style = DEFAULT_FONT_STYLE
+		}
+
+		// the sizes of the sidebar are much larger than window titles, so only the family and style are used
+		for role := range theme.Fonts {
+			theme.Fonts[role].Family = family
+			if FontRole(role) != SUBTITLE_FONT {
+				theme.Fonts[role].Style = style
+			}
+		}
+	}
+
+	// inner gaps of 0 (the default of sway) would leave no space around the text of the rows
+	if config.Gaps > 0 {
+		theme.Spacing = config.Gaps
+	}
+
+	return theme, nil
+}
+
+// Splits a pango font description ("[pango:]Family[,Fallback] [Style...] [Size]") into its parts
+func parsePangoFont(description string) (family string, style string, size float64) {
+	description = strings.TrimPrefix(strings.TrimSpace(description), "pango:")
+
+	words := strings.Fields(description)
+
+	// the size is the last word, if it is a number
+	if len(words) > 0 {
+		if parsed, err := strconv.ParseFloat(strings.TrimSuffix(words[len(words)-1], "px"), 64); err == nil {
+			size = parsed
+			words = words[:len(words)-1]
+		}
+	}
+
+	// style words come before the size
+	var styles []string
+	for len(words) > 1 && pango_style_words[strings.ToLower(words[len(words)-1])] {
+		styles = append([]string{words[len(words)-1]}, styles...)
+		words = words[:len(words)-1]
+	}
+
+	// only the first of a list of families
+	family = strings.TrimSpace(strings.Split(strings.Join(words, " "), ",")[0])
+
+	return family, strings.Join(styles, " "), size
+}
+
+/*
+##############################################################
+# Section: Sway config
+##############################################################
+*/
+
+// Gets the sway config, from sway itself if it is running, otherwise from the config file
+func loadSwayConfig() (config SwayConfig, err error) {
+	if sway_ipc != nil {
+		text, err := sway_ipc.GetConfig()
+		if err == nil {
+			// includes are relative to the loaded file
+			dir := ""
+			if version, err := sway_ipc.GetVersion(); err == nil && version.LoadedConfigFileName != "" {
+				dir = filepath.Dir(version.LoadedConfigFileName)
+			}
+
+			return parseSwayConfig(strings.NewReader(text), dir, map[string]bool{}), nil
+		}
+	}
+
+	path, err := findSwayConfigFile()
+	if err != nil {
+		return config, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return config, err
+	}
+	defer file.Close()
+
+	return parseSwayConfig(file, filepath.Dir(path), map[string]bool{path: true}), nil
+}
+
+// Finds the config file the way sway does
+func findSwayConfigFile() (path string, err error) {
+	var candidates []string
+
+	home, err := getHomePath()
+	if err == nil {
+		candidates = append(candidates, filepath.Join(home, ".sway", "config"))
+	}
+	if config_home, err := getXDGConfigHome(); err == nil {
+		candidates = append(candidates, filepath.Join(config_home, "sway", "config"))
+	}
+	candidates = append(candidates, "/etc/sway/config")
+
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("no sway config found (tried %s)", strings.Join(candidates, ", "))
+}
+
+// Reads the client colors, the font and the inner gaps from a sway config.
+// dir is the directory of the file (for relative includes), included files are only read once.
+func parseSwayConfig(reader io.Reader, dir string, included map[string]bool) (config SwayConfig) {
+	sway_reader := swayConfigReader{
+		config:    SwayConfig{Clients: make(map[string]SwayClientColors)},
+		variables: make(map[string]string),
+		included:  included,
+	}
+
+	sway_reader.read(reader, dir)
+
+	return sway_reader.config
+}
+
+// Reads a file of the config, the included files where they are included (so later directives win, like in sway)
+func (sway_reader *swayConfigReader) read(reader io.Reader, dir string) {
+	config := &sway_reader.config
+	depth := 0
+
+	for _, line := range readSwayConfigLines(reader) {
+		// blocks (like bar { ... }) have their own colors and fonts
+		if strings.HasSuffix(line, "{") {
+			depth++
+			continue
+		}
+		if line == "}" {
+			if depth > 0 {
+				depth--
+			}
+			continue
+		}
+		if depth > 0 {
+			continue
+		}
+
+		words := strings.Fields(line)
+
+		if words[0] == "set" && len(words) >= 3 && strings.HasPrefix(words[1], "$") {
+			sway_reader.variables[words[1]] = strings.Join(words[2:], " ")
+			continue
+		}
+
+		words = strings.Fields(expandSwayVariables(line, sway_reader.variables))
+
+		switch {
+		case words[0] == "font" && len(words) > 1:
+			config.Font = strings.Join(words[1:], " ")
+
+		case words[0] == "gaps" && len(words) == 3 && words[1] == "inner":
+			if gaps, err := strconv.Atoi(words[2]); err == nil && gaps >= 0 {
+				config.Gaps = int32(gaps)
+			}
+
+		case strings.HasPrefix(words[0], "client.") && len(words) >= 4:
+			class := strings.TrimPrefix(words[0], "client.")
+
+			// indicator and child_border are optional, they default to the background
+			values := words[1:]
+			for len(values) < len(SwayClientColors{}) {
+				values = append(values, words[2])
+			}
+
+			var colors SwayClientColors
+			valid := true
+			for i := range colors {
+				if parseSwayColor(values[i], &colors[i]) != nil {
+					valid = false
+				}
+			}
+
+			if valid {
+				config.Clients[class] = colors
+			}
+
+		case words[0] == "include" && len(words) == 2:
+			pattern := expandHomePath(words[1])
+			if !filepath.IsAbs(pattern) {
+				pattern = filepath.Join(dir, pattern)
+			}
+
+			paths, _ := filepath.Glob(pattern)
+			for _, path := range paths {
+				if sway_reader.included[path] {
+					continue
+				}
+				sway_reader.included[path] = true
+
+				file, err := os.Open(path)
+				if err != nil {
+					continue
+				}
+				sway_reader.read(file, filepath.Dir(path))
+				file.Close()
+			}
+		}
+	}
+}
+
+// Reads the lines of a sway config without comments, joining continued lines
+func readSwayConfigLines(reader io.Reader) (lines []string) {
+	scanner := bufio.NewScanner(reader)
+
+	continued := ""
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasSuffix(line, "\\") {
+			continued += strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+
+		line = strings.TrimSpace(continued + line)
+		continued = ""
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// Replaces $variables, longest names first (so $a does not break $ab)
+func expandSwayVariables(line string, variables map[string]string) (expanded string) {
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return len(names[i]) > len(names[j])
+	})
+
+	for _, name := range names {
+		line = strings.Replace(line, name, variables[name], -1)
+	}
+
+	return line
+}
+
+// Parses a sway color, #rrggbb or #rrggbbaa (the alpha is ignored)
+func parseSwayColor(value string, color *uint32) (err error) {
+	if len(value) == 9 && strings.HasPrefix(value, "#") {
+		value = value[:7]
+	}
+
+	return parseColor(value, color)
+}
+
+// Replaces a leading ~ with the home directory
+func expandHomePath(path string) (expanded string) {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home, err := getHomePath()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, path[2:])
+}
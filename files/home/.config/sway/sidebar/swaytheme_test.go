@@ -0,0 +1,182 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Parses the config file of dir
+func parseTestSwayConfig(t *testing.T, dir string) (config SwayConfig) {
+	t.Helper()
+
+	path := filepath.Join(dir, "config")
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	return parseSwayConfig(file, dir, map[string]bool{path: true})
+}
+
+func TestParseSwayConfig(t *testing.T) {
+	config := parseSwayConfig(strings.NewReader(`# colors
+set $bg #10171e
+set $bgx #ffffff
+font pango:Noto Sans \
+	Bold 11
+
+client.focused #4c7899 #285577 #ffffff #2e9ef4 #285577
+client.unfocused #333333 $bg #888888
+client.urgent #2f343a #900000ff #ffffff #900000 #900000
+client.focused_inactive #333333 #5f676a
+client.placeholder not a color #ffffff #000000
+
+gaps inner 12
+gaps outer 4
+
+bar {
+	font pango:Other 20
+	colors {
+		background #000000
+	}
+	gaps inner 30
+}
+`), "", map[string]bool{})
+
+	want := SwayConfig{
+		Clients: map[string]SwayClientColors{
+			"focused": {0x4c7899, 0x285577, 0xffffff, 0x2e9ef4, 0x285577},
+
+			// indicator and child_border default to the background, and the longest variable wins
+			"unfocused": {0x333333, 0x10171e, 0x888888, 0x10171e, 0x10171e},
+
+			// the alpha is ignored
+			"urgent": {0x2f343a, 0x900000, 0xffffff, 0x900000, 0x900000},
+		},
+
+		// blocks are skipped
+		Font: "pango:Noto Sans Bold 11",
+		Gaps: 12,
+	}
+
+	// too short and invalid client lines are skipped
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("got %+v, want %+v", config, want)
+	}
+}
+
+func TestParseSwayConfigIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"config": `set $accent #2e9ef4
+include colors
+gaps inner $gap
+include conf.d/*
+font pango:First 10
+client.focused #000000 $focused_bg #ffffff $accent
+include config
+`,
+		// variables of included files are seen after the include, and the other way around
+		"colors": `set $focused_bg #285577
+set $gap 6
+client.urgent #000000 #900000 #ffffff $accent
+`,
+		"conf.d/10-font":   "font pango:Second 10\ngaps inner 20\n",
+		"conf.d/20-colors": "client.unfocused #000000 $focused_bg #888888\n",
+		"conf.d/30-again":  "include ../colors\n",
+	})
+
+	config := parseTestSwayConfig(t, dir)
+
+	want := SwayConfig{
+		Clients: map[string]SwayClientColors{
+			"focused":   {0x000000, 0x285577, 0xffffff, 0x2e9ef4, 0x285577},
+			"urgent":    {0x000000, 0x900000, 0xffffff, 0x2e9ef4, 0x900000},
+			"unfocused": {0x000000, 0x285577, 0x888888, 0x285577, 0x285577},
+		},
+
+		// later directives win, in included files too
+		Font: "pango:First 10",
+		Gaps: 20,
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("got %+v, want %+v", config, want)
+	}
+}
+
+func TestParsePangoFont(t *testing.T) {
+	tests := []struct {
+		description string
+		family      string
+		style       string
+		size        float64
+	}{
+		{"pango:DejaVu Sans Mono 10", "DejaVu Sans Mono", "", 10},
+		{"pango:Noto Sans Bold Italic 9.5", "Noto Sans", "Bold Italic", 9.5},
+		{"  Hack semi-bold 12px ", "Hack", "semi-bold", 12},
+		{"pango:Iosevka, Font Awesome 5 Free 11", "Iosevka", "", 11},
+		{"monospace", "monospace", "", 0},
+
+		// the family is never a style
+		{"Bold", "Bold", "", 0},
+		{"pango:Light 10", "Light", "", 10},
+		{"", "", "", 0},
+	}
+
+	for _, test := range tests {
+		family, style, size := parsePangoFont(test.description)
+		if family != test.family || style != test.style || size != test.size {
+			t.Errorf("parsePangoFont(%q) = %q, %q, %v, want %q, %q, %v",
+				test.description, family, style, size, test.family, test.style, test.size)
+		}
+	}
+}
+
+func TestLoadSwayTheme(t *testing.T) {
+	useTestConfig(t, nil)
+	config_home := os.Getenv("XDG_CONFIG_HOME")
+	t.Setenv("HOME", t.TempDir())
+
+	old_ipc := sway_ipc
+	sway_ipc = nil
+	t.Cleanup(func() { sway_ipc = old_ipc })
+
+	writeTestFiles(t, config_home, map[string]string{"sway/config": `font pango:Noto Sans Bold 10
+client.focused #4c7899 #285577 #ffffff #2e9ef4
+client.urgent #2f343a #900000 #ffffff
+gaps inner 14
+`})
+
+	theme, err := loadTheme(SWAY_THEME)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := builtin_themes[DEFAULT_THEME]
+	want.Name = SWAY_THEME
+	want.Colors[FOREGROUND] = 0xffffff
+	want.Colors[ACCENT] = 0x2e9ef4
+	want.Colors[SELECTION] = 0x285577
+	want.Colors[URGENT] = 0x900000
+	want.Spacing = 14
+	for role := range want.Fonts {
+		want.Fonts[role].Family = "Noto Sans"
+		if FontRole(role) != SUBTITLE_FONT {
+			want.Fonts[role].Style = "Bold"
+		}
+	}
+
+	if theme != want {
+		t.Errorf("got %+v, want %+v", theme, want)
+	}
+
+	// gaps inner 0 keeps the spacing
+	writeTestFiles(t, config_home, map[string]string{"sway/config": "gaps inner 0\n"})
+	if theme, err := loadTheme(SWAY_THEME); err != nil || theme.Spacing != builtin_themes[DEFAULT_THEME].Spacing {
+		t.Errorf("got spacing %d, %v", theme.Spacing, err)
+	}
+}
@@ -0,0 +1,371 @@
+package main
+
+/*
+##############################################################
+# Section: Imports
+##############################################################
+*/
+
+import (
+	"unicode"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/*
+########################
+# Subsection: TextInput
+########################
+*/
+
+// Width of the caret in pixels
+const CARET_WIDTH = 2
+
+// A single line of editable text.
+// Items do not get events by themselves, so the window handler has to pass them to HandleEvent.
+type TextInput struct {
+	position Vector
+	size     Vector
+	font     FontRole
+	color    ColorRole
+	bgcolor  ColorRole
+
+	// shown in MUTED while the text is empty
+	placeholder string
+
+	// background of the selected text
+	selcolor ColorRole
+
+	// called whenever the user changed the text
+	on_change func(text string)
+
+	text []rune
+
+	// rune index of the caret. The selection goes from anchor to caret.
+	caret  int
+	anchor int
+
+	// how many pixels the text is scrolled to the left, so the caret stays visible
+	scroll int32
+
+	dirty bool
+}
+
+// Gets the current text
+func (input *TextInput) Text() (text string) {
+	return string(input.text)
+}
+
+// Replaces the text and moves the caret to its end (does not call on_change)
+func (input *TextInput) SetText(text string) {
+	input.text = []rune(text)
+	input.caret = len(input.text)
+	input.anchor = input.caret
+	input.dirty = true
+}
+
+// Gets the selected rune range, start <= end
+func (input *TextInput) Selection() (start int, end int) {
+	if input.anchor < input.caret {
+		return input.anchor, input.caret
+	}
+	return input.caret, input.anchor
+}
+
+// Gets the selected text
+func (input *TextInput) SelectedText() (text string) {
+	start, end := input.Selection()
+	return string(input.text[start:end])
+}
+
+// Selects the whole text
+func (input *TextInput) SelectAll() {
+	input.anchor = 0
+	input.caret = len(input.text)
+}
+
+// Inserts text at the caret, replacing the selection
+func (input *TextInput) Insert(text string) {
+	input.deleteSelection()
+
+	// it is a single line
+	var runes []rune
+	for _, r := range text {
+		if r == '\n' || r == '\r' || r == '\t' {
+			r = ' '
+		}
+		if unicode.IsPrint(r) {
+			runes = append(runes, r)
+		}
+	}
+
+	input.text = append(input.text[:input.caret], append(runes, input.text[input.caret:]...)...)
+	input.caret += len(runes)
+	input.anchor = input.caret
+}
+
+// Removes the selected text, returns false if nothing was selected
+func (input *TextInput) deleteSelection() (deleted bool) {
+	start, end := input.Selection()
+	if start == end {
+		return false
+	}
+
+	input.text = append(input.text[:start], input.text[end:]...)
+	input.caret = start
+	input.anchor = start
+	return true
+}
+
+// Moves the caret. When selecting, the anchor stays where it is.
+func (input *TextInput) moveCaret(position int, selecting bool) {
+	if position < 0 {
+		position = 0
+	}
+	if position > len(input.text) {
+		position = len(input.text)
+	}
+
+	input.caret = position
+	if !selecting {
+		input.anchor = position
+	}
+}
+
+// Finds the beginning of the word before the caret
+func (input *TextInput) wordLeft() (position int) {
+	position = input.caret
+	for position > 0 && !isWordRune(input.text[position-1]) {
+		position--
+	}
+	for position > 0 && isWordRune(input.text[position-1]) {
+		position--
+	}
+	return position
+}
+
+// Finds the end of the word after the caret
+func (input *TextInput) wordRight() (position int) {
+	position = input.caret
+	for position < len(input.text) && !isWordRune(input.text[position]) {
+		position++
+	}
+	for position < len(input.text) && isWordRune(input.text[position]) {
+		position++
+	}
+	return position
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// Handles keyboard and text input events. Returns true if the event was used.
+func (input *TextInput) HandleEvent(event sdl.Event) (handled bool) {
+	old := string(input.text)
+
+	switch ty := event.(type) {
+	case *sdl.TextInputEvent:
+		input.Insert(GetInputText(ty))
+		handled = true
+	case *sdl.KeyboardEvent:
+		if ty.Type != sdl.KEYDOWN {
+			return false
+		}
+		handled = input.handleKey(ty.Keysym.Sym, sdl.Keymod(ty.Keysym.Mod))
+	}
+
+	if handled {
+		input.dirty = true
+	}
+
+	if handled && input.on_change != nil && string(input.text) != old {
+		input.on_change(string(input.text))
+	}
+
+	return handled
+}
+
+func (input *TextInput) handleKey(key sdl.Keycode, mod sdl.Keymod) (handled bool) {
+	ctrl := mod&sdl.KMOD_CTRL != 0
+	shift := mod&sdl.KMOD_SHIFT != 0
+
+	switch key {
+	case sdl.K_LEFT:
+		start, _ := input.Selection()
+		switch {
+		case ctrl:
+			input.moveCaret(input.wordLeft(), shift)
+		case !shift && input.caret != input.anchor:
+			// collapse the selection to its beginning
+			input.moveCaret(start, false)
+		default:
+			input.moveCaret(input.caret-1, shift)
+		}
+	case sdl.K_RIGHT:
+		_, end := input.Selection()
+		switch {
+		case ctrl:
+			input.moveCaret(input.wordRight(), shift)
+		case !shift && input.caret != input.anchor:
+			input.moveCaret(end, false)
+		default:
+			input.moveCaret(input.caret+1, shift)
+		}
+	// once the caret is at that end, Home and End are left to others (like the results of the run window)
+	case sdl.K_HOME:
+		if input.caret == 0 && input.anchor == 0 {
+			return false
+		}
+		input.moveCaret(0, shift)
+	case sdl.K_END:
+		if input.caret == len(input.text) && input.anchor == len(input.text) {
+			return false
+		}
+		input.moveCaret(len(input.text), shift)
+	case sdl.K_BACKSPACE:
+		if !input.deleteSelection() {
+			if ctrl {
+				input.moveCaret(input.wordLeft(), true)
+			} else {
+				input.moveCaret(input.caret-1, true)
+			}
+			input.deleteSelection()
+		}
+	case sdl.K_DELETE:
+		if !input.deleteSelection() {
+			if ctrl {
+				input.moveCaret(input.wordRight(), true)
+			} else {
+				input.moveCaret(input.caret+1, true)
+			}
+			input.deleteSelection()
+		}
+	case sdl.K_a:
+		if !ctrl {
+			return false
+		}
+		input.SelectAll()
+	case sdl.K_c, sdl.K_x:
+		if !ctrl {
+			return false
+		}
+		if input.caret != input.anchor {
+			sdl.SetClipboardText(input.SelectedText())
+			if key == sdl.K_x {
+				input.deleteSelection()
+			}
+		}
+	case sdl.K_v:
+		if !ctrl {
+			return false
+		}
+		text, err := sdl.GetClipboardText()
+		if err != nil {
+			return true
+		}
+		input.Insert(text)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// Draw the item onto the canvas
+func (input *TextInput) Draw(canvas Canvas) (err error) {
+	input.dirty = false
+	canvas.FillRect(Vector{0, 0}, input.size, input.bgcolor.RGB())
+
+	font, err := getFont(input.font)
+	if err != nil {
+		return err
+	}
+
+	height := int32(font.Height())
+	coordinate_y := (input.size.y - height) / 2
+
+	// x offsets of caret and selection
+	offset := func(i int) (x int32, err error) {
+		w, _, err := font.SizeUTF8(string(input.text[:i]))
+		return int32(w), err
+	}
+
+	caret_x, err := offset(input.caret)
+	if err != nil {
+		return err
+	}
+
+	// scroll just enough to keep the caret inside of the item
+	if caret_x-input.scroll > input.size.x-CARET_WIDTH {
+		input.scroll = caret_x - input.size.x + CARET_WIDTH
+	}
+	if caret_x-input.scroll < 0 {
+		input.scroll = caret_x
+	}
+
+	if len(input.text) == 0 {
+		input.scroll = 0
+
+		err = canvas.DrawText(input.placeholder, font, MUTED.RGB(), Vector{0, coordinate_y})
+		if err != nil {
+			return err
+		}
+	} else {
+		// selection goes behind the text
+		start, end := input.Selection()
+		if start != end {
+			start_x, err := offset(start)
+			if err != nil {
+				return err
+			}
+			end_x, err := offset(end)
+			if err != nil {
+				return err
+			}
+
+			canvas.FillRect(Vector{start_x - input.scroll, coordinate_y}, Vector{end_x - start_x, height}, input.selcolor.RGB())
+		}
+
+		// blended text is transparent around the glyphs, so the selection stays visible
+		err = canvas.DrawText(string(input.text), font, input.color.RGB(), Vector{-input.scroll, coordinate_y})
+		if err != nil {
+			return err
+		}
+	}
+
+	return canvas.FillRect(Vector{caret_x - input.scroll, coordinate_y}, Vector{CARET_WIDTH, height}, input.color.RGB())
+}
+
+// Getters and setters
+
+func (input *TextInput) GetPosition() (position Vector) {
+	return input.position
+}
+
+func (input *TextInput) SetPosition(position Vector) {
+	input.position = position
+	input.dirty = true
+}
+
+func (input *TextInput) GetSize() (size Vector) {
+	return input.size
+}
+
+func (input *TextInput) SetSize(size Vector) {
+	input.size = size
+	input.dirty = true
+}
+
+func (input *TextInput) Dirty() bool {
+	return input.dirty
+}
+
+// Gets the height of a line (the text scrolls, so it needs no width)
+func (input *TextInput) Measure() (size Vector) {
+	font, err := getFont(input.font)
+	if err != nil {
+		return Vector{0, 0}
+	}
+	return Vector{0, int32(font.Height())}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+)
+
+func newTestInput(text string) (input *TextInput) {
+	input = &TextInput{font: HEADER_FONT, color: FOREGROUND, bgcolor: SEPARATOR, selcolor: SELECTION, placeholder: "Search"}
+	input.SetText(text)
+	return input
+}
+
+func TestTextInputCaret(t *testing.T) {
+	useTestTheme(t)
+
+	font, err := getFont(HEADER_FONT)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := newTestInput("hello")
+	size := Vector{200, 30}
+	img := drawTestItem(t, input, size)
+
+	w, h, err := font.SizeUTF8("hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	top := (int(size.y) - h) / 2
+
+	// the caret is right after the text, as high as a line
+	for y := top; y < top+h; y++ {
+		for x := w; x < w+CARET_WIDTH; x++ {
+			if !hasColor(img, x, y, FOREGROUND.RGB()) {
+				t.Fatalf("pixel %d,%d of the caret is %v", x, y, img.RGBAAt(x, y))
+			}
+		}
+	}
+	if !hasColor(img, w+CARET_WIDTH, top+h/2, SEPARATOR.RGB()) {
+		t.Errorf("the caret is wider than %d pixels", CARET_WIDTH)
+	}
+}
+
+func TestTextInputSelection(t *testing.T) {
+	useTestTheme(t)
+
+	input := newTestInput("hello")
+	input.SelectAll()
+	size := Vector{200, 30}
+	img := drawTestItem(t, input, size)
+
+	font, err := getFont(HEADER_FONT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, h, err := font.SizeUTF8("hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	top := (int(size.y) - h) / 2
+
+	// the selection is behind the whole text, next to it is the background
+	if !hasColor(img, 0, top, SELECTION.RGB()) || !hasColor(img, w-1, top+h-1, SELECTION.RGB()) {
+		t.Errorf("the selection does not cover the text")
+	}
+	if !hasColor(img, w+CARET_WIDTH+1, top, SEPARATOR.RGB()) {
+		t.Errorf("the selection reaches out of the text")
+	}
+}
+
+func TestTextInputPlaceholder(t *testing.T) {
+	useTestTheme(t)
+
+	muted := 0
+	foreground := 0
+	img := drawTestItem(t, newTestInput(""), Vector{200, 30})
+	for y := 0; y < 30; y++ {
+		for x := CARET_WIDTH; x < 200; x++ {
+			if hasColor(img, x, y, MUTED.RGB()) {
+				muted++
+			}
+			if hasColor(img, x, y, FOREGROUND.RGB()) {
+				foreground++
+			}
+		}
+	}
+
+	if muted == 0 || foreground != 0 {
+		t.Errorf("the placeholder has %d muted and %d foreground pixels", muted, foreground)
+	}
+}
+
+func TestTextInputScroll(t *testing.T) {
+	useTestTheme(t)
+
+	input := newTestInput("a text that is much too long for the input")
+	size := Vector{100, 30}
+	img := drawTestItem(t, input, size)
+
+	// the text is scrolled so the caret is at the right edge
+	if input.scroll <= 0 {
+		t.Fatalf("the text is not scrolled")
+	}
+	if !hasColor(img, int(size.x)-1, int(size.y)/2, FOREGROUND.RGB()) {
+		t.Errorf("the caret is not at the right edge")
+	}
+
+	// and back at the start with the caret
+	input.moveCaret(0, false)
+	drawTestItem(t, input, size)
+	if input.scroll != 0 {
+		t.Errorf("the text is still scrolled by %d with the caret at the start", input.scroll)
+	}
+}
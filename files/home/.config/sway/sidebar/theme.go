@@ -0,0 +1,348 @@
+package main
+
+/*
+##############################################################
+# Section: Imports
+##############################################################
+*/
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+/*
+######################################################################################################
+######################################################################################################
+## Chapter: Themes																					##
+######################################################################################################
+######################################################################################################
+*/
+
+/*
+##############################################################
+# Section: Constants & Fields
+##############################################################
+*/
+
+// What a color is used for. Items store roles, so they follow the theme.
+type ColorRole int
+
+const (
+	BACKGROUND ColorRole = iota
+	FOREGROUND
+	MUTED     // secondary text
+	SEPARATOR // bars between items, empty desktops
+	ACCENT    // search matches, selected rows, focused workspaces
+	SELECTION // selected text
+	URGENT    // urgent workspaces
+
+	COLOR_ROLE_COUNT
+)
+
+// What a font is used for
+type FontRole int
+
+const (
+	TITLE_FONT    FontRole = iota // window titles
+	SUBTITLE_FONT                 // workspace names
+	HEADER_FONT                   // list entries, search box
+	BODY_FONT                     // descriptions
+
+	FONT_ROLE_COUNT
+)
+
+// The names of the roles in theme and config files
+var color_role_names = [COLOR_ROLE_COUNT]string{"Background", "Foreground", "Muted", "Separator", "Accent", "Selection", "Urgent"}
+var font_role_names = [FONT_ROLE_COUNT]string{"Title", "Subtitle", "Header", "Body"}
+
+// Theme files are found in this subdirectory of the config directory, as <name>.theme
+const THEMES_DIR = "themes"
+
+// Theme files have a single group
+const THEME_GROUP = "Theme"
+
+// A font at a size. Family is a font family (looked up with fontconfig) or the path of a font file.
+type Font struct {
+	Family string
+	Style  string
+	Size   int
+}
+
+type Theme struct {
+	Name   string
+	Colors [COLOR_ROLE_COUNT]uint32
+	Fonts  [FONT_ROLE_COUNT]Font
+
+	// the space between the items of the windows, in pixels
+	Spacing int32
+}
+
+const DEFAULT_THEME = "dark"
+
+var builtin_themes = map[string]Theme{
+	"dark": {
+		Name:   "dark",
+		Colors: [COLOR_ROLE_COUNT]uint32{0x10171e, 0xffffff, 0xa0a1a7, 0x20272e, 0x2e9ef4, 0x285577, 0x900000},
+		Fonts: [FONT_ROLE_COUNT]Font{
+			{"DejaVu Sans", "Regular", 128},
+			{"DejaVu Sans", "Bold", 64},
+			{"DejaVu Sans", "Regular", 32},
+			{"DejaVu Sans", "Regular", 24},
+		},
+		Spacing: 8,
+	},
+	"light": {
+		Name:   "light",
+		Colors: [COLOR_ROLE_COUNT]uint32{0xf5f6f7, 0x1e2329, 0x5c6370, 0xd8dbe0, 0x1a73e8, 0xa8c7fa, 0xc62828},
+		Fonts: [FONT_ROLE_COUNT]Font{
+			{"DejaVu Sans", "Regular", 128},
+			{"DejaVu Sans", "Bold", 64},
+			{"DejaVu Sans", "Regular", 32},
+			{"DejaVu Sans", "Regular", 24},
+		},
+		Spacing: 8,
+	},
+}
+
+// The theme all items are drawn with
+var current_theme = builtin_themes[DEFAULT_THEME]
+
+/*
+##############################################################
+# Section: Roles
+##############################################################
+*/
+
+// Gets the color of the role in the current theme
+func (role ColorRole) RGB() uint32 {
+	return current_theme.Colors[role]
+}
+
+// Gets the font of the role in the current theme
+func (role FontRole) Font() Font {
+	return current_theme.Fonts[role]
+}
+
+// Gets the space between the items of the windows in the current theme
+func spacing() int32 {
+	return current_theme.Spacing
+}
+
+/*
+##############################################################
+# Section: Loading
+##############################################################
+*/
+
+// Gets a theme by name, from the themes directory or the built in ones (or the sway config, see SWAY_THEME)
+func loadTheme(name string) (theme Theme, err error) {
+	return loadInheritedTheme(name, nil)
+}
+
+// Like loadTheme, for a theme inherited by the given chain of theme files (the first one inherits from the next one).
+// A theme file inheriting a theme of its own name gets the built in one.
+func loadInheritedTheme(name string, chain []string) (theme Theme, err error) {
+	if name == SWAY_THEME {
+		return loadSwayTheme()
+	}
+
+	config_dir, err := getConfigDir()
+	if err == nil && !containsString(chain, name) {
+		path := filepath.Join(config_dir, THEMES_DIR, name+".theme")
+
+		theme, err = loadThemeFile(path, chain)
+		if err == nil {
+			return theme, nil
+		}
+
+		// broken theme files are still used (they report what is wrong with them)
+		if _, ok := err.(DesktopEntryErrors); ok {
+			return theme, err
+		}
+		if !os.IsNotExist(err) {
+			return builtin_themes[DEFAULT_THEME], err
+		}
+	}
+
+	theme, ok := builtin_themes[name]
+	if !ok && containsString(chain, name) {
+		return builtin_themes[DEFAULT_THEME], fmt.Errorf("theme %q inherits itself (%s)", name, strings.Join(append(chain, name), " -> "))
+	}
+	if !ok {
+		return builtin_themes[DEFAULT_THEME], fmt.Errorf("unknown theme %q", name)
+	}
+
+	return theme, nil
+}
+
+// Reads a theme file. Themes start as a copy of the theme in Inherits (the default theme if missing),
+// so they only have to contain what they change. chain are the theme files inheriting this one.
+func loadThemeFile(path string, chain []string) (theme Theme, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return theme, err
+	}
+	defer file.Close()
+
+	var errs DesktopEntryErrors
+	report := func(line int, format string, args ...interface{}) {
+		errs = append(errs, DesktopEntryError{File: path, Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	parsed, err := parseKeyFile(file)
+	if parse_errs, ok := err.(DesktopEntryErrors); ok {
+		for _, parse_err := range parse_errs {
+			parse_err.File = path
+			errs = append(errs, parse_err)
+		}
+	} else if err != nil {
+		return theme, err
+	}
+
+	group := parsed.Group(THEME_GROUP)
+	if group == nil {
+		report(0, "missing group [%s]", THEME_GROUP)
+		return builtin_themes[DEFAULT_THEME], errs
+	}
+	for _, other := range parsed.Groups {
+		if other != group {
+			report(other.Line, "unknown group [%s], theme files only have [%s]", other.Name, THEME_GROUP)
+		}
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), ".theme")
+
+	theme = builtin_themes[DEFAULT_THEME]
+	if group.Has("Inherits") {
+		// the errors of inherited theme files are reported, but their themes still used
+		theme, err = loadInheritedTheme(group.String("Inherits"), append(chain, name))
+		if inherited_errs, ok := err.(DesktopEntryErrors); ok {
+			errs = append(errs, inherited_errs...)
+		} else if err != nil {
+			report(group.values["Inherits"].line, "%v", err)
+		}
+	}
+	theme.Name = name
+
+	for _, key := range group.Keys() {
+		if key == "Inherits" {
+			continue
+		}
+
+		known, err := theme.Set(key, group.String(key))
+		if !known {
+			report(group.values[key].line, "unknown key %q in [%s]", key, THEME_GROUP)
+		} else if err != nil {
+			report(group.values[key].line, "invalid value for %s: %v", key, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return theme, errs
+	}
+
+	return theme, nil
+}
+
+// Sets a value of the theme by its key in theme files:
+// the color role names (e.g. Background=#10171e), <font role>Font (a family or file), <font role>Style and <font role>Size,
+// and Spacing. known is false if there is no such key.
+func (theme *Theme) Set(key string, value string) (known bool, err error) {
+	if key == "Spacing" {
+		spacing := int(theme.Spacing)
+		err = parsePositiveInt(value, &spacing)
+		theme.Spacing = int32(spacing)
+		return true, err
+	}
+
+	for role, name := range color_role_names {
+		if key == name {
+			return true, parseColor(value, &theme.Colors[role])
+		}
+	}
+
+	for role, name := range font_role_names {
+		switch key {
+		case name + "Font":
+			return true, parseFontFamily(value, &theme.Fonts[role].Family)
+		case name + "Style":
+			return true, parseNonEmpty(value, &theme.Fonts[role].Style)
+		case name + "Size":
+			return true, parsePositiveInt(value, &theme.Fonts[role].Size)
+		}
+	}
+
+	return false, nil
+}
+
+// Checks if key is a key of theme files
+func isThemeKey(key string) bool {
+	var theme Theme
+	known, _ := theme.Set(key, "")
+	return known
+}
+
+/*
+##############################################################
+# Section: Value types
+##############################################################
+*/
+
+// Parses a color as #rrggbb
+func parseColor(value string, color *uint32) (err error) {
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) != 6 {
+		return fmt.Errorf("%q is not a color, expected #rrggbb", value)
+	}
+
+	parsed, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return fmt.Errorf("%q is not a color, expected #rrggbb", value)
+	}
+
+	*color = uint32(parsed)
+	return nil
+}
+
+// Parses a positive integer
+func parsePositiveInt(value string, number *int) (err error) {
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		return fmt.Errorf("%q is not a positive integer", value)
+	}
+
+	*number = parsed
+	return nil
+}
+
+// Parses a font family, or the path of a font file (which has to exist)
+func parseFontFamily(value string, family *string) (err error) {
+	if !strings.Contains(value, "/") {
+		return parseNonEmpty(value, family)
+	}
+
+	info, err := os.Stat(expandHomePath(value))
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory, expected a font file", value)
+	}
+
+	*family = value
+	return nil
+}
+
+// Parses anything but an empty value
+func parseNonEmpty(value string, text *string) (err error) {
+	if strings.TrimSpace(value) == "" {
+		return fmt.Errorf("expected a value")
+	}
+
+	*text = value
+	return nil
+}
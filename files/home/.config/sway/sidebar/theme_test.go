@@ -0,0 +1,166 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestLoadThemeFile(t *testing.T) {
+	useTestConfig(t, map[string]string{"themes/mine.theme": `[Theme]
+Accent=#ff8800
+HeaderSize=40
+BodyFont=Noto Sans
+`})
+
+	theme, err := loadTheme("mine")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// without Inherits, a theme file starts from the default theme
+	want := builtin_themes[DEFAULT_THEME]
+	want.Name = "mine"
+	want.Colors[ACCENT] = 0xff8800
+	want.Fonts[HEADER_FONT].Size = 40
+	want.Fonts[BODY_FONT].Family = "Noto Sans"
+	if theme != want {
+		t.Errorf("got %+v, want %+v", theme, want)
+	}
+
+	// the built in themes, unless a file has their name
+	if theme, err := loadTheme("light"); err != nil || theme != builtin_themes["light"] {
+		t.Errorf("got %+v, %v for light", theme, err)
+	}
+	if _, err := loadTheme("missing"); err == nil || err.Error() != `unknown theme "missing"` {
+		t.Errorf("got %v for a missing theme", err)
+	}
+}
+
+func TestThemeInheritsChain(t *testing.T) {
+	useTestConfig(t, map[string]string{
+		"themes/a.theme": "[Theme]\nInherits=b\nAccent=#0000aa\n",
+		"themes/b.theme": "[Theme]\nInherits=light\nAccent=#0000bb\nUrgent=#bb0000\n",
+
+		// a file of a built in theme changes it, starting from the built in one
+		"themes/dark.theme":  "[Theme]\nInherits=dark\nMuted=#dd0000\n",
+		"themes/dark2.theme": "[Theme]\nInherits=dark\n",
+	})
+
+	theme, err := loadTheme("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each theme changes what it inherits
+	want := builtin_themes["light"]
+	want.Name = "a"
+	want.Colors[ACCENT] = 0x0000aa
+	want.Colors[URGENT] = 0xbb0000
+	if theme != want {
+		t.Errorf("got %+v, want %+v", theme, want)
+	}
+
+	want = builtin_themes["dark"]
+	want.Name = "dark2"
+	want.Colors[MUTED] = 0xdd0000
+	if theme, err := loadTheme("dark2"); err != nil || theme != want {
+		t.Errorf("got %+v, %v, want %+v", theme, err, want)
+	}
+}
+
+func TestThemeInheritsErrors(t *testing.T) {
+	useTestConfig(t, map[string]string{
+		"themes/self.theme": "[Theme]\nAccent=#111111\nInherits=self\n",
+
+		"themes/a.theme": "[Theme]\nInherits=b\nAccent=#0000aa\n",
+		"themes/b.theme": "[Theme]\n\nInherits=a\n",
+
+		"themes/orphan.theme": "[Theme]\nInherits=missing\nAccent=#222222\n",
+
+		// the errors of inherited files are theirs
+		"themes/child.theme":  "[Theme]\nInherits=broken\nAccent=#333333\n",
+		"themes/broken.theme": "[Theme]\nAccent=blue\nInherits=light\n",
+	})
+
+	tests := []struct {
+		name string
+		// the file with the errors
+		file   string
+		errors []expectedError
+		// the theme is still used, starting from the default theme where inheriting failed
+		base   string
+		accent uint32
+	}{
+		{"self", "self.theme", []expectedError{{3, `theme "self" inherits itself (self -> self)`}}, "dark", 0x111111},
+		{"a", "b.theme", []expectedError{{3, `theme "a" inherits itself (a -> b -> a)`}}, "dark", 0x0000aa},
+		{"orphan", "orphan.theme", []expectedError{{2, `unknown theme "missing"`}}, "dark", 0x222222},
+		{"child", "broken.theme", []expectedError{{2, "invalid value for Accent"}}, "light", 0x333333},
+	}
+
+	for _, test := range tests {
+		theme, err := loadTheme(test.name)
+
+		checkErrors(t, err, test.file, test.errors)
+
+		want := builtin_themes[test.base]
+		if theme.Name != test.name || theme.Colors[ACCENT] != test.accent || theme.Colors[BACKGROUND] != want.Colors[BACKGROUND] {
+			t.Errorf("%s: got %+v, want %s with accent %06x", test.name, theme, test.base, test.accent)
+		}
+	}
+}
+
+func TestThemeFileErrors(t *testing.T) {
+	useTestConfig(t, map[string]string{
+		"themes/errors.theme": `[Theme]
+Accent=#12345
+Accent2=#123456
+TitleSize=0
+BodyFont=/does/not/exist.ttf
+junk
+
+[Colors]
+Background=#000000
+`,
+		"themes/empty.theme": "# no groups\n",
+	})
+
+	theme, err := loadTheme("errors")
+	checkErrors(t, err, "errors.theme", []expectedError{
+		{6, "expected key=value"},
+		{8, "unknown group [Colors], theme files only have [Theme]"},
+		{2, "invalid value for Accent"},
+		{3, `unknown key "Accent2" in [Theme]`},
+		{4, "invalid value for TitleSize"},
+		{5, "invalid value for BodyFont"},
+	})
+
+	// invalid values keep those of the inherited theme
+	if theme.Colors != builtin_themes[DEFAULT_THEME].Colors || theme.Fonts != builtin_themes[DEFAULT_THEME].Fonts {
+		t.Errorf("invalid values changed the theme to %+v", theme)
+	}
+
+	_, err = loadTheme("empty")
+	checkErrors(t, err, "empty.theme", []expectedError{{0, "missing group [Theme]"}})
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		value string
+		ok    bool
+		color uint32
+	}{
+		{"#10171e", true, 0x10171e},
+		{"10171E", true, 0x10171e},
+		{"#fff", false, 0},
+		{"#10171e00", false, 0},
+		{"#10171g", false, 0},
+		{"", false, 0},
+	}
+
+	for _, test := range tests {
+		var color uint32
+		err := parseColor(test.value, &color)
+		if (err == nil) != test.ok || color != test.color {
+			t.Errorf("parseColor(%q) = %06x, %v", test.value, color, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+/*
+##############################################################
+# Section: Imports
+##############################################################
+*/
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*
+######################################################################################################
+######################################################################################################
+## Chapter: XDG Base Directories																		##
+######################################################################################################
+######################################################################################################
+*/
+
+/*
+##############################################################
+# Section: Constants & Fields
+##############################################################
+*/
+
+// The subdirectory of the sidebar in every base directory
+const SIDEBAR_SUBDIR = "sway/sidebar"
+
+// Defaults of the spec for unset variables (relative to the home directory)
+const (
+	DEFAULT_CONFIG_HOME = ".config"
+	DEFAULT_DATA_HOME   = ".local/share"
+	DEFAULT_CACHE_HOME  = ".cache"
+	DEFAULT_STATE_HOME  = ".local/state"
+	DEFAULT_DATA_DIRS   = "/usr/local/share/:/usr/share/"
+)
+
+/*
+##############################################################
+# Section: Base directories
+##############################################################
+*/
+
+// Gets a base directory from its variable, or the default in the home directory.
+// The spec says relative paths in the variables have to be ignored.
+func getXDGHome(variable string, fallback string) (path string, err error) {
+	if path = os.Getenv(variable); filepath.IsAbs(path) {
+		return path, nil
+	}
+
+	home, err := getHomePath()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, fallback), nil
+}
+
+// $XDG_CONFIG_HOME, user specific configuration
+func getXDGConfigHome() (path string, err error) {
+	return getXDGHome("XDG_CONFIG_HOME", DEFAULT_CONFIG_HOME)
+}
+
+// $XDG_DATA_HOME, user specific data (the first of the data directories)
+func getXDGDataHome() (path string, err error) {
+	return getXDGHome("XDG_DATA_HOME", DEFAULT_DATA_HOME)
+}
+
+// $XDG_CACHE_HOME, data that can be deleted at any time
+func getXDGCacheHome() (path string, err error) {
+	return getXDGHome("XDG_CACHE_HOME", DEFAULT_CACHE_HOME)
+}
+
+// $XDG_STATE_HOME, data that should survive restarts, but is not worth backing up (e.g. history)
+func getXDGStateHome() (path string, err error) {
+	return getXDGHome("XDG_STATE_HOME", DEFAULT_STATE_HOME)
+}
+
+// $XDG_DATA_DIRS, the system wide data directories by precedence
+func getXDGDataDirs() (paths []string) {
+	value := os.Getenv("XDG_DATA_DIRS")
+	if value == "" {
+		value = DEFAULT_DATA_DIRS
+	}
+
+	for _, path := range strings.Split(value, ":") {
+		if filepath.IsAbs(path) {
+			paths = append(paths, filepath.Clean(path))
+		}
+	}
+
+	return paths
+}
+
+// Gets the subdirectory in $XDG_DATA_HOME and every $XDG_DATA_DIRS entry, by precedence
+// (e.g. "applications" or "icons")
+func getXDGDataPaths(subdir string) (paths []string) {
+	if data_home, err := getXDGDataHome(); err == nil {
+		paths = append(paths, filepath.Join(data_home, subdir))
+	}
+
+	for _, dir := range getXDGDataDirs() {
+		paths = append(paths, filepath.Join(dir, subdir))
+	}
+
+	return paths
+}
+
+/*
+##############################################################
+# Section: Sidebar directories
+##############################################################
+*/
+
+// Gets the config directory of the sidebar
+func getConfigDir() (path string, err error) {
+	path, err = getXDGConfigHome()
+	return filepath.Join(path, SIDEBAR_SUBDIR), err
+}
+
+// Gets the cache directory of the sidebar
+func getCacheDir() (path string, err error) {
+	path, err = getXDGCacheHome()
+	return filepath.Join(path, SIDEBAR_SUBDIR), err
+}
+
+// Gets the state directory of the sidebar
+func getStateDir() (path string, err error) {
+	path, err = getXDGStateHome()
+	return filepath.Join(path, SIDEBAR_SUBDIR), err
+}
+
+// Gets the directories containing desktop files, the ones first in the list take precedence
+func getDesktopFileDirs() (paths []string) {
+	return getXDGDataPaths("applications")
+}
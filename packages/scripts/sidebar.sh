@@ -2,6 +2,9 @@
 
 mkdir -p ~/.config/sway/sidebar/
 
+# svg rendering for icons, fonts for drawing without sdl (offscreen rendering) and font fallbacks
+go get -v github.com/srwiley/oksvg github.com/srwiley/rasterx golang.org/x/image/font/opentype golang.org/x/image/font/sfnt golang.org/x/image/math/fixed
+
 cd $basedir/files/home/.config/sway/sidebar/
 go build
 